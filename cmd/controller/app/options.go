@@ -18,6 +18,8 @@ package app
 
 import (
 	"github.com/spf13/pflag"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
 )
 
 type ServerRunOptions struct {
@@ -28,6 +30,18 @@ type ServerRunOptions struct {
 	ApiServerBurst       int
 	Workers              int
 	EnableLeaderElection bool
+	PodGroupKeys         string
+	// EnableWorkloadController creates pod groups for workloads. It watches all pods of the cluster.
+	EnableWorkloadController bool
+	WorkloadResources        []string
+	// PodGroupTTLSecondsAfterFinished is the default TTL of finished pod groups, which are kept if it is negative.
+	PodGroupTTLSecondsAfterFinished int
+	DeletePodsAfterFinished         bool
+	EnableElasticQuotaController    bool
+	// WebhookPort is the port of the admission webhooks, which are not served if it is zero.
+	WebhookPort     int
+	WebhookCertFile string
+	WebhookKeyFile  string
 }
 
 func NewServerRunOptions() *ServerRunOptions {
@@ -44,4 +58,21 @@ func (s *ServerRunOptions) addAllFlags() {
 	pflag.IntVar(&s.ApiServerBurst, "burst", 10, "burst of query apiserver.")
 	pflag.IntVar(&s.Workers, "workers", 1, "workers of scheduler-plugin-controllers.")
 	pflag.BoolVar(&s.EnableLeaderElection, "enableLeaderElection", s.EnableLeaderElection, "If EnableLeaderElection for controller.")
+	pflag.StringVar(&s.PodGroupKeys, "podGroupKeys", s.PodGroupKeys,
+		"Ordered label and annotation keys of the pod group name of pods, as a YAML or JSON list in the same form as podGroupKeys "+
+			"of the Coscheduling args, e.g. [{type: Annotation, key: scheduling.k8s.io/group-name}]. Defaults to the label "+util.PodGroupLabel+".")
+	pflag.BoolVar(&s.EnableWorkloadController, "enableWorkloadController", s.EnableWorkloadController,
+		"If pod groups are created for workloads annotated with min-available. It watches all pods of the cluster.")
+	pflag.StringSliceVar(&s.WorkloadResources, "workloadResources", []string{"jobs.v1.batch", "statefulsets.v1.apps", "replicasets.v1.apps"},
+		"Workload resources, in the form of resource.version.group, that pod groups are created for if annotated with min-available.")
+	pflag.IntVar(&s.PodGroupTTLSecondsAfterFinished, "podGroupTTLSecondsAfterFinished", -1,
+		"Default TTL of finished and failed pod groups that do not set ttlSecondsAfterFinished. Negative to keep them.")
+	pflag.BoolVar(&s.DeletePodsAfterFinished, "deletePodsAfterFinished", s.DeletePodsAfterFinished,
+		"If the pods of a finished pod group are deleted together with it when its TTL expires.")
+	pflag.BoolVar(&s.EnableElasticQuotaController, "enableElasticQuotaController", s.EnableElasticQuotaController,
+		"If the used resources of elastic quotas are reported in their status. It watches all pods of the cluster.")
+	pflag.IntVar(&s.WebhookPort, "webhookPort", 0,
+		"Port of the admission webhooks that default and validate pod groups and elastic quotas. 0 to disable them.")
+	pflag.StringVar(&s.WebhookCertFile, "webhookCertFile", s.WebhookCertFile, "TLS certificate file of the admission webhooks.")
+	pflag.StringVar(&s.WebhookKeyFile, "webhookKeyFile", s.WebhookKeyFile, "TLS private key file of the admission webhooks.")
 }
@@ -18,9 +18,15 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"os"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -29,12 +35,12 @@ import (
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/klog/v2"
-	"os"
 
 	"sigs.k8s.io/scheduler-plugins/pkg/controller"
 	pgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
 	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
 	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	"sigs.k8s.io/scheduler-plugins/pkg/webhook"
 )
 
 func newConfig(kubeconfig, master string, inCluster bool) (*restclient.Config, error) {
@@ -53,6 +59,19 @@ func newConfig(kubeconfig, master string, inCluster bool) (*restclient.Config, e
 	return config, nil
 }
 
+// parseWorkloadResources parses resources in the form of resource.version.group, e.g. mpijobs.v1.kubeflow.org.
+func parseWorkloadResources(resources []string) ([]schema.GroupVersionResource, error) {
+	var result []schema.GroupVersionResource
+	for _, resource := range resources {
+		gvr, _ := schema.ParseResourceArg(resource)
+		if gvr == nil {
+			return nil, fmt.Errorf("invalid workload resource %q", resource)
+		}
+		result = append(result, *gvr)
+	}
+	return result, nil
+}
+
 func Run(s *ServerRunOptions) error {
 	ctx := context.Background()
 	config, err := newConfig(s.KubeConfig, s.MasterUrl, s.InCluster)
@@ -69,14 +88,73 @@ func Run(s *ServerRunOptions) error {
 	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
 	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
+	pgKeys, err := util.ParsePodGroupKeys(s.PodGroupKeys)
+	if err != nil {
+		return err
+	}
+	resolver := util.NewPodGroupResolver(pgKeys)
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithTweakListOptions(func(opt *metav1.ListOptions) {
-		opt.LabelSelector = util.PodGroupLabel
+		opt.LabelSelector = resolver.LabelSelector()
 	}))
 	podInformer := informerFactory.Core().V1().Pods()
-	ctrl := controller.NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient)
+	var ttlSecondsAfterFinished *int32
+	if s.PodGroupTTLSecondsAfterFinished >= 0 {
+		ttl := int32(s.PodGroupTTLSecondsAfterFinished)
+		ttlSecondsAfterFinished = &ttl
+	}
+	ctrl := controller.NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, resolver,
+		ttlSecondsAfterFinished, s.DeletePodsAfterFinished)
+
+	// The pod informer of the PodGroup controller only lists the members of PodGroups, while the workload
+	// controller labels pods without PodGroup, and the usage of ElasticQuotas accounts for all the pods of
+	// their namespaces.
+	var allPodsInformerFactory informers.SharedInformerFactory
+	if s.EnableWorkloadController || s.EnableElasticQuotaController {
+		allPodsInformerFactory = informers.NewSharedInformerFactory(kubeClient, 0)
+	}
+	var workloadCtrl *controller.WorkloadController
+	var dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
+	if s.EnableWorkloadController {
+		resources, err := parseWorkloadResources(s.WorkloadResources)
+		if err != nil {
+			return err
+		}
+		dynamicClient := dynamic.NewForConfigOrDie(config)
+		dynamicInformerFactory = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+		workloadCtrl = controller.NewWorkloadController(kubeClient, dynamicInformerFactory, pgInformer,
+			allPodsInformerFactory.Core().V1().Pods(), pgClient, resolver, resources)
+	}
+
+	var eqCtrl *controller.ElasticQuotaController
+	if s.EnableElasticQuotaController {
+		eqInformer := pgInformerFactory.Scheduling().V1alpha1().ElasticQuotas()
+		eqCtrl = controller.NewElasticQuotaController(eqInformer, allPodsInformerFactory.Core().V1().Pods(), pgClient)
+	}
 	pgInformerFactory.Start(stopCh)
 	informerFactory.Start(stopCh)
+	if dynamicInformerFactory != nil {
+		dynamicInformerFactory.Start(stopCh)
+	}
+	if allPodsInformerFactory != nil {
+		allPodsInformerFactory.Start(stopCh)
+	}
+
+	// The webhooks are served by every replica, not only by the leader.
+	if s.WebhookPort > 0 {
+		wh := webhook.NewWebhook(pgInformer, pgClient, resolver)
+		go func() {
+			if err := wh.Run(fmt.Sprintf(":%d", s.WebhookPort), s.WebhookCertFile, s.WebhookKeyFile, stopCh); err != nil {
+				klog.Fatalf("error serving admission webhooks: %v", err)
+			}
+		}()
+	}
 	run := func(ctx context.Context) {
+		if workloadCtrl != nil {
+			go workloadCtrl.Run(s.Workers, ctx.Done())
+		}
+		if eqCtrl != nil {
+			go eqCtrl.Run(s.Workers, ctx.Done())
+		}
 		ctrl.Run(s.Workers, ctx.Done())
 	}
 
@@ -3,6 +3,7 @@ module sigs.k8s.io/scheduler-plugins
 go 1.15
 
 require (
+	github.com/evanphx/json-patch v4.9.0+incompatible
 	github.com/google/go-cmp v0.4.0
 	github.com/google/uuid v1.1.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
@@ -19,6 +20,7 @@ require (
 	k8s.io/kube-openapi v0.0.0-20200805222855-6aeccd4b50c6
 	k8s.io/kube-scheduler v0.19.0
 	k8s.io/kubernetes v1.19.0
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
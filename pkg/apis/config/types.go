@@ -19,6 +19,7 @@ package config
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	schedulerconfig "k8s.io/kube-scheduler/config/v1"
+	schedulerconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -29,14 +30,74 @@ type CoschedulingArgs struct {
 
 	// PermitWaitingTime is the wait timeout in seconds.
 	PermitWaitingTimeSeconds int64
+	// PodGroupInitialBackoffSeconds is the initial backoff in seconds of a podgroup that failed to be scheduled.
+	PodGroupInitialBackoffSeconds int64
+	// PodGroupMaxBackoffSeconds is the max backoff in seconds of a podgroup that failed to be scheduled.
+	PodGroupMaxBackoffSeconds int64
 	// DeniedPGExpirationTimeSeconds is the expiration time of the denied podgroup store.
+	// Deprecated: it is only used as the fixed backoff of podgroups if the backoff is not set.
 	DeniedPGExpirationTimeSeconds int64
+	// ReservationThresholdSeconds is the waiting time in seconds of a podgroup after which nodes are
+	// reserved for it. Reservation is disabled if it is 0.
+	ReservationThresholdSeconds int64
+	// EnableBackfill allows backfill pods to be placed on nodes reserved for podgroups and to use the
+	// resources held by members waiting in permit. Backfill pods are preempted first by the members of
+	// podgroups, and evicted once the podgroups holding their resources are permitted. The NodeResourcesFit
+	// filter should be disabled when it is set, as the Coscheduling filter runs it instead, and lets backfill
+	// pods pass it with the resources held by the waiting members released.
+	EnableBackfill bool
+	// NodeResourcesFitArgs are the args of the NodeResourcesFit filter run by the Coscheduling filter when
+	// EnableBackfill is set.
+	NodeResourcesFitArgs *schedulerconfigv1beta1.NodeResourcesFitArgs
+	// BackfillPriorityThreshold is the priority below which pods not belonging to any podgroup are backfill pods.
+	BackfillPriorityThreshold int32
+	// DisablePodGroupCRD runs coscheduling without watching the PodGroup CRD. PodGroups are then only
+	// declared by the labels or annotations of pods, and their states are kept in memory.
+	DisablePodGroupCRD bool
+	// PodGroupKeys is the ordered list of label and annotation keys that hold the PodGroup name of pods.
+	// The first key set on a pod is used. Defaults to the label pod-group.scheduling.sigs.k8s.io.
+	PodGroupKeys []PodGroupKey
+	// LocalityMode scores nodes by the members of the same podgroup already placed on them, or in
+	// their topology domains of LocalityTopologyKey: Pack favors nodes with more members, and Spread
+	// favors nodes with fewer. Members are placed with no awareness of each other if it is empty.
+	LocalityMode LocalityMode
+	// LocalityTopologyKey is the key of the node label whose domains the members are counted in by
+	// LocalityMode. Members on the same node are counted if it is empty.
+	LocalityTopologyKey string
 	// KubeMaster is the url of api-server
 	KubeMaster string
 	// KubeConfigPath for scheduler
 	KubeConfigPath string
 }
 
+// LocalityMode is the mode of scoring nodes by the members of a podgroup placed on them.
+type LocalityMode string
+
+const (
+	// LocalityPack favors nodes with more members of the podgroup, to reduce cross-node traffic.
+	LocalityPack LocalityMode = "Pack"
+	// LocalitySpread favors nodes with fewer members of the podgroup, for resilience.
+	LocalitySpread LocalityMode = "Spread"
+)
+
+// PodGroupKeyType is the type of a PodGroup key.
+type PodGroupKeyType string
+
+const (
+	// PodGroupKeyLabel is the type of label keys.
+	PodGroupKeyLabel PodGroupKeyType = "Label"
+	// PodGroupKeyAnnotation is the type of annotation keys.
+	PodGroupKeyAnnotation PodGroupKeyType = "Annotation"
+)
+
+// PodGroupKey is a label or annotation key that holds the PodGroup name of pods.
+type PodGroupKey struct {
+	// Type is the type of the key, either Label or Annotation.
+	Type PodGroupKeyType
+	// Key is the label or annotation key.
+	Key string
+}
+
 // ModeType is a "string" type.
 type ModeType string
 
@@ -72,4 +133,10 @@ type CapacitySchedulingArgs struct {
 
 	// KubeConfigPath is the path of kubeconfig.
 	KubeConfigPath string
+	// DisablePodGroupCRD admits pods without watching the PodGroup CRD. PodGroups are then only
+	// declared by the labels or annotations of pods.
+	DisablePodGroupCRD bool
+	// PodGroupKeys is the ordered list of label and annotation keys that hold the PodGroup name of pods,
+	// which should be the same as in CoschedulingArgs. Defaults to the label pod-group.scheduling.sigs.k8s.io.
+	PodGroupKeys []PodGroupKey
 }
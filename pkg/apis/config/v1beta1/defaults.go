@@ -24,7 +24,9 @@ import (
 
 var (
 	defaultPermitWaitingTimeSeconds      int64 = 60
-	defaultDeniedPGExpirationTimeSeconds int64 = 20
+	defaultPodGroupInitialBackoffSeconds int64 = 3
+	defaultPodGroupMaxBackoffSeconds     int64 = 60
+	defaultPodGroupKeys                        = []PodGroupKey{{Type: PodGroupKeyLabel, Key: "pod-group.scheduling.sigs.k8s.io"}}
 
 	defaultNodeResourcesAllocatableMode = Least
 
@@ -45,8 +47,25 @@ func SetDefaultsCoschedulingArgs(obj *CoschedulingArgs) {
 	if obj.PermitWaitingTimeSeconds == nil {
 		obj.PermitWaitingTimeSeconds = &defaultPermitWaitingTimeSeconds
 	}
-	if obj.DeniedPGExpirationTimeSeconds == nil {
-		obj.DeniedPGExpirationTimeSeconds = &defaultDeniedPGExpirationTimeSeconds
+	// The deprecated DeniedPGExpirationTimeSeconds denies podgroups for a fixed time, which is a backoff
+	// that does not grow.
+	if obj.DeniedPGExpirationTimeSeconds != nil && obj.PodGroupInitialBackoffSeconds == nil && obj.PodGroupMaxBackoffSeconds == nil {
+		obj.PodGroupInitialBackoffSeconds = obj.DeniedPGExpirationTimeSeconds
+		obj.PodGroupMaxBackoffSeconds = obj.DeniedPGExpirationTimeSeconds
+	}
+	if obj.PodGroupInitialBackoffSeconds == nil {
+		obj.PodGroupInitialBackoffSeconds = &defaultPodGroupInitialBackoffSeconds
+	}
+	if obj.PodGroupMaxBackoffSeconds == nil {
+		obj.PodGroupMaxBackoffSeconds = &defaultPodGroupMaxBackoffSeconds
+	}
+	if len(obj.PodGroupKeys) == 0 {
+		obj.PodGroupKeys = defaultPodGroupKeys
+	}
+	for i := range obj.PodGroupKeys {
+		if obj.PodGroupKeys[i].Type == "" {
+			obj.PodGroupKeys[i].Type = PodGroupKeyLabel
+		}
 	}
 
 	// TODO(k/k#96427): get KubeConfigPath and KubeMaster from configuration or command args.
@@ -68,4 +87,12 @@ func SetDefaultsCapacitySchedulingArgs(obj *CapacitySchedulingArgs) {
 	if obj.KubeConfigPath == nil {
 		obj.KubeConfigPath = &defaultKubeConfigPath
 	}
+	if len(obj.PodGroupKeys) == 0 {
+		obj.PodGroupKeys = defaultPodGroupKeys
+	}
+	for i := range obj.PodGroupKeys {
+		if obj.PodGroupKeys[i].Type == "" {
+			obj.PodGroupKeys[i].Type = PodGroupKeyLabel
+		}
+	}
 }
@@ -19,6 +19,7 @@ package v1beta1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	schedulerconfig "k8s.io/kube-scheduler/config/v1"
+	schedulerconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -29,14 +30,75 @@ type CoschedulingArgs struct {
 
 	// PermitWaitingTime is the wait timeout in seconds.
 	PermitWaitingTimeSeconds *int64 `json:"permitWaitingTimeSeconds,omitempty"`
+	// PodGroupInitialBackoffSeconds is the initial backoff in seconds of a podgroup that failed to be scheduled.
+	PodGroupInitialBackoffSeconds *int64 `json:"podGroupInitialBackoffSeconds,omitempty"`
+	// PodGroupMaxBackoffSeconds is the max backoff in seconds of a podgroup that failed to be scheduled.
+	PodGroupMaxBackoffSeconds *int64 `json:"podGroupMaxBackoffSeconds,omitempty"`
 	// DeniedPGExpirationTimeSeconds is the expiration time of the denied podgroup store.
+	// Deprecated: use PodGroupInitialBackoffSeconds and PodGroupMaxBackoffSeconds instead. If neither
+	// of them is set, both default to DeniedPGExpirationTimeSeconds, i.e., podgroups back off for a fixed time.
 	DeniedPGExpirationTimeSeconds *int64 `json:"deniedPGExpirationTimeSeconds,omitempty"`
+	// ReservationThresholdSeconds is the waiting time in seconds of a podgroup after which nodes are
+	// reserved for it. Reservation is disabled if it is not set or 0.
+	ReservationThresholdSeconds *int64 `json:"reservationThresholdSeconds,omitempty"`
+	// EnableBackfill allows backfill pods to be placed on nodes reserved for podgroups and to use the
+	// resources held by members waiting in permit. Backfill pods are preempted first by the members of
+	// podgroups, and evicted once the podgroups holding their resources are permitted. The NodeResourcesFit
+	// filter should be disabled when it is set, as the Coscheduling filter runs it instead, and lets backfill
+	// pods pass it with the resources held by the waiting members released.
+	EnableBackfill *bool `json:"enableBackfill,omitempty"`
+	// NodeResourcesFitArgs are the args of the NodeResourcesFit filter run by the Coscheduling filter when
+	// EnableBackfill is set.
+	NodeResourcesFitArgs *schedulerconfigv1beta1.NodeResourcesFitArgs `json:"nodeResourcesFitArgs,omitempty"`
+	// BackfillPriorityThreshold is the priority below which pods not belonging to any podgroup are backfill pods.
+	BackfillPriorityThreshold *int32 `json:"backfillPriorityThreshold,omitempty"`
+	// DisablePodGroupCRD runs coscheduling without watching the PodGroup CRD. PodGroups are then only
+	// declared by the labels or annotations of pods, and their states are kept in memory.
+	DisablePodGroupCRD *bool `json:"disablePodGroupCRD,omitempty"`
+	// PodGroupKeys is the ordered list of label and annotation keys that hold the PodGroup name of pods.
+	// The first key set on a pod is used. Defaults to the label pod-group.scheduling.sigs.k8s.io.
+	PodGroupKeys []PodGroupKey `json:"podGroupKeys,omitempty"`
+	// LocalityMode scores nodes by the members of the same podgroup already placed on them, or in
+	// their topology domains of LocalityTopologyKey: Pack favors nodes with more members, and Spread
+	// favors nodes with fewer. Members are placed with no awareness of each other if it is not set.
+	LocalityMode LocalityMode `json:"localityMode,omitempty"`
+	// LocalityTopologyKey is the key of the node label whose domains the members are counted in by
+	// LocalityMode. Members on the same node are counted if it is not set.
+	LocalityTopologyKey *string `json:"localityTopologyKey,omitempty"`
 	// KubeMaster is the url of api-server
 	KubeMaster *string `json:"kubeMaster,omitempty"`
 	// KubeConfigPath for scheduler
 	KubeConfigPath *string `json:"kubeConfigPath,omitempty"`
 }
 
+// LocalityMode is the mode of scoring nodes by the members of a podgroup placed on them.
+type LocalityMode string
+
+const (
+	// LocalityPack favors nodes with more members of the podgroup, to reduce cross-node traffic.
+	LocalityPack LocalityMode = "Pack"
+	// LocalitySpread favors nodes with fewer members of the podgroup, for resilience.
+	LocalitySpread LocalityMode = "Spread"
+)
+
+// PodGroupKeyType is the type of a PodGroup key.
+type PodGroupKeyType string
+
+const (
+	// PodGroupKeyLabel is the type of label keys.
+	PodGroupKeyLabel PodGroupKeyType = "Label"
+	// PodGroupKeyAnnotation is the type of annotation keys.
+	PodGroupKeyAnnotation PodGroupKeyType = "Annotation"
+)
+
+// PodGroupKey is a label or annotation key that holds the PodGroup name of pods.
+type PodGroupKey struct {
+	// Type is the type of the key, either Label or Annotation.
+	Type PodGroupKeyType `json:"type,omitempty"`
+	// Key is the label or annotation key.
+	Key string `json:"key"`
+}
+
 // ModeType is a type "string".
 type ModeType string
 
@@ -72,4 +134,10 @@ type CapacitySchedulingArgs struct {
 
 	// KubeConfigPath is the path of kubeconfig.
 	KubeConfigPath *string `json:"kubeConfigPath,omitempty"`
+	// DisablePodGroupCRD admits pods without watching the PodGroup CRD. PodGroups are then only
+	// declared by the labels or annotations of pods.
+	DisablePodGroupCRD *bool `json:"disablePodGroupCRD,omitempty"`
+	// PodGroupKeys is the ordered list of label and annotation keys that hold the PodGroup name of pods,
+	// which should be the same as in CoschedulingArgs. Defaults to the label pod-group.scheduling.sigs.k8s.io.
+	PodGroupKeys []PodGroupKey `json:"podGroupKeys,omitempty"`
 }
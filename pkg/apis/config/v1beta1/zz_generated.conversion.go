@@ -27,6 +27,7 @@ import (
 	conversion "k8s.io/apimachinery/pkg/conversion"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	configv1 "k8s.io/kube-scheduler/config/v1"
+	configv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 	config "sigs.k8s.io/scheduler-plugins/pkg/apis/config"
 )
 
@@ -67,6 +68,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*PodGroupKey)(nil), (*config.PodGroupKey)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_PodGroupKey_To_config_PodGroupKey(a.(*PodGroupKey), b.(*config.PodGroupKey), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.PodGroupKey)(nil), (*PodGroupKey)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_PodGroupKey_To_v1beta1_PodGroupKey(a.(*config.PodGroupKey), b.(*PodGroupKey), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -74,6 +85,10 @@ func autoConvert_v1beta1_CapacitySchedulingArgs_To_config_CapacitySchedulingArgs
 	if err := v1.Convert_Pointer_string_To_string(&in.KubeConfigPath, &out.KubeConfigPath, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.DisablePodGroupCRD, &out.DisablePodGroupCRD, s); err != nil {
+		return err
+	}
+	out.PodGroupKeys = *(*[]config.PodGroupKey)(unsafe.Pointer(&in.PodGroupKeys))
 	return nil
 }
 
@@ -86,6 +101,10 @@ func autoConvert_config_CapacitySchedulingArgs_To_v1beta1_CapacitySchedulingArgs
 	if err := v1.Convert_string_To_Pointer_string(&in.KubeConfigPath, &out.KubeConfigPath, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.DisablePodGroupCRD, &out.DisablePodGroupCRD, s); err != nil {
+		return err
+	}
+	out.PodGroupKeys = *(*[]PodGroupKey)(unsafe.Pointer(&in.PodGroupKeys))
 	return nil
 }
 
@@ -98,9 +117,33 @@ func autoConvert_v1beta1_CoschedulingArgs_To_config_CoschedulingArgs(in *Cosched
 	if err := v1.Convert_Pointer_int64_To_int64(&in.PermitWaitingTimeSeconds, &out.PermitWaitingTimeSeconds, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.PodGroupInitialBackoffSeconds, &out.PodGroupInitialBackoffSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds, s); err != nil {
+		return err
+	}
 	if err := v1.Convert_Pointer_int64_To_int64(&in.DeniedPGExpirationTimeSeconds, &out.DeniedPGExpirationTimeSeconds, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.ReservationThresholdSeconds, &out.ReservationThresholdSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.EnableBackfill, &out.EnableBackfill, s); err != nil {
+		return err
+	}
+	out.NodeResourcesFitArgs = (*configv1beta1.NodeResourcesFitArgs)(unsafe.Pointer(in.NodeResourcesFitArgs))
+	if err := v1.Convert_Pointer_int32_To_int32(&in.BackfillPriorityThreshold, &out.BackfillPriorityThreshold, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.DisablePodGroupCRD, &out.DisablePodGroupCRD, s); err != nil {
+		return err
+	}
+	out.PodGroupKeys = *(*[]config.PodGroupKey)(unsafe.Pointer(&in.PodGroupKeys))
+	out.LocalityMode = config.LocalityMode(in.LocalityMode)
+	if err := v1.Convert_Pointer_string_To_string(&in.LocalityTopologyKey, &out.LocalityTopologyKey, s); err != nil {
+		return err
+	}
 	if err := v1.Convert_Pointer_string_To_string(&in.KubeMaster, &out.KubeMaster, s); err != nil {
 		return err
 	}
@@ -119,9 +162,33 @@ func autoConvert_config_CoschedulingArgs_To_v1beta1_CoschedulingArgs(in *config.
 	if err := v1.Convert_int64_To_Pointer_int64(&in.PermitWaitingTimeSeconds, &out.PermitWaitingTimeSeconds, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.PodGroupInitialBackoffSeconds, &out.PodGroupInitialBackoffSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds, s); err != nil {
+		return err
+	}
 	if err := v1.Convert_int64_To_Pointer_int64(&in.DeniedPGExpirationTimeSeconds, &out.DeniedPGExpirationTimeSeconds, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.ReservationThresholdSeconds, &out.ReservationThresholdSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.EnableBackfill, &out.EnableBackfill, s); err != nil {
+		return err
+	}
+	out.NodeResourcesFitArgs = (*configv1beta1.NodeResourcesFitArgs)(unsafe.Pointer(in.NodeResourcesFitArgs))
+	if err := v1.Convert_int32_To_Pointer_int32(&in.BackfillPriorityThreshold, &out.BackfillPriorityThreshold, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.DisablePodGroupCRD, &out.DisablePodGroupCRD, s); err != nil {
+		return err
+	}
+	out.PodGroupKeys = *(*[]PodGroupKey)(unsafe.Pointer(&in.PodGroupKeys))
+	out.LocalityMode = LocalityMode(in.LocalityMode)
+	if err := v1.Convert_string_To_Pointer_string(&in.LocalityTopologyKey, &out.LocalityTopologyKey, s); err != nil {
+		return err
+	}
 	if err := v1.Convert_string_To_Pointer_string(&in.KubeMaster, &out.KubeMaster, s); err != nil {
 		return err
 	}
@@ -157,3 +224,25 @@ func autoConvert_config_NodeResourcesAllocatableArgs_To_v1beta1_NodeResourcesAll
 func Convert_config_NodeResourcesAllocatableArgs_To_v1beta1_NodeResourcesAllocatableArgs(in *config.NodeResourcesAllocatableArgs, out *NodeResourcesAllocatableArgs, s conversion.Scope) error {
 	return autoConvert_config_NodeResourcesAllocatableArgs_To_v1beta1_NodeResourcesAllocatableArgs(in, out, s)
 }
+
+func autoConvert_v1beta1_PodGroupKey_To_config_PodGroupKey(in *PodGroupKey, out *config.PodGroupKey, s conversion.Scope) error {
+	out.Type = config.PodGroupKeyType(in.Type)
+	out.Key = in.Key
+	return nil
+}
+
+// Convert_v1beta1_PodGroupKey_To_config_PodGroupKey is an autogenerated conversion function.
+func Convert_v1beta1_PodGroupKey_To_config_PodGroupKey(in *PodGroupKey, out *config.PodGroupKey, s conversion.Scope) error {
+	return autoConvert_v1beta1_PodGroupKey_To_config_PodGroupKey(in, out, s)
+}
+
+func autoConvert_config_PodGroupKey_To_v1beta1_PodGroupKey(in *config.PodGroupKey, out *PodGroupKey, s conversion.Scope) error {
+	out.Type = PodGroupKeyType(in.Type)
+	out.Key = in.Key
+	return nil
+}
+
+// Convert_config_PodGroupKey_To_v1beta1_PodGroupKey is an autogenerated conversion function.
+func Convert_config_PodGroupKey_To_v1beta1_PodGroupKey(in *config.PodGroupKey, out *PodGroupKey, s conversion.Scope) error {
+	return autoConvert_config_PodGroupKey_To_v1beta1_PodGroupKey(in, out, s)
+}
@@ -23,6 +23,7 @@ package v1beta1
 import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	v1 "k8s.io/kube-scheduler/config/v1"
+	configv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -34,6 +35,16 @@ func (in *CapacitySchedulingArgs) DeepCopyInto(out *CapacitySchedulingArgs) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.DisablePodGroupCRD != nil {
+		in, out := &in.DisablePodGroupCRD, &out.DisablePodGroupCRD
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PodGroupKeys != nil {
+		in, out := &in.PodGroupKeys, &out.PodGroupKeys
+		*out = make([]PodGroupKey, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -64,11 +75,56 @@ func (in *CoschedulingArgs) DeepCopyInto(out *CoschedulingArgs) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.PodGroupInitialBackoffSeconds != nil {
+		in, out := &in.PodGroupInitialBackoffSeconds, &out.PodGroupInitialBackoffSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PodGroupMaxBackoffSeconds != nil {
+		in, out := &in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	if in.DeniedPGExpirationTimeSeconds != nil {
 		in, out := &in.DeniedPGExpirationTimeSeconds, &out.DeniedPGExpirationTimeSeconds
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ReservationThresholdSeconds != nil {
+		in, out := &in.ReservationThresholdSeconds, &out.ReservationThresholdSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.EnableBackfill != nil {
+		in, out := &in.EnableBackfill, &out.EnableBackfill
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NodeResourcesFitArgs != nil {
+		in, out := &in.NodeResourcesFitArgs, &out.NodeResourcesFitArgs
+		*out = new(configv1beta1.NodeResourcesFitArgs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackfillPriorityThreshold != nil {
+		in, out := &in.BackfillPriorityThreshold, &out.BackfillPriorityThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DisablePodGroupCRD != nil {
+		in, out := &in.DisablePodGroupCRD, &out.DisablePodGroupCRD
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PodGroupKeys != nil {
+		in, out := &in.PodGroupKeys, &out.PodGroupKeys
+		*out = make([]PodGroupKey, len(*in))
+		copy(*out, *in)
+	}
+	if in.LocalityTopologyKey != nil {
+		in, out := &in.LocalityTopologyKey, &out.LocalityTopologyKey
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -119,3 +175,19 @@ func (in *NodeResourcesAllocatableArgs) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodGroupKey) DeepCopyInto(out *PodGroupKey) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroupKey.
+func (in *PodGroupKey) DeepCopy() *PodGroupKey {
+	if in == nil {
+		return nil
+	}
+	out := new(PodGroupKey)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -23,12 +23,23 @@ package config
 import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	v1 "k8s.io/kube-scheduler/config/v1"
+	v1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CapacitySchedulingArgs) DeepCopyInto(out *CapacitySchedulingArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.NodeResourcesFitArgs != nil {
+		in, out := &in.NodeResourcesFitArgs, &out.NodeResourcesFitArgs
+		*out = new(v1beta1.NodeResourcesFitArgs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodGroupKeys != nil {
+		in, out := &in.PodGroupKeys, &out.PodGroupKeys
+		*out = make([]PodGroupKey, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -54,6 +65,11 @@ func (in *CapacitySchedulingArgs) DeepCopyObject() runtime.Object {
 func (in *CoschedulingArgs) DeepCopyInto(out *CoschedulingArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.PodGroupKeys != nil {
+		in, out := &in.PodGroupKeys, &out.PodGroupKeys
+		*out = make([]PodGroupKey, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -104,3 +120,19 @@ func (in *NodeResourcesAllocatableArgs) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodGroupKey) DeepCopyInto(out *PodGroupKey) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroupKey.
+func (in *PodGroupKey) DeepCopy() *PodGroupKey {
+	if in == nil {
+		return nil
+	}
+	out := new(PodGroupKey)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -22,7 +22,6 @@ import (
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ElasticQuota sets elastic quota restrictions per namespace
@@ -100,6 +99,8 @@ const (
 
 	// PodGroupUnknown means part of `spec.minMember` pods are running but the other part can not
 	// be scheduled, e.g. not enough resource; scheduler will wait for related controller to recover it.
+	// A running pod group whose members are deleted, below `spec.minMember`, is in this phase until
+	// `spec.minMember` pods are running again.
 	PodGroupUnknown PodGroupPhase = "Unknown"
 
 	// PodGroupFinish means all of `spec.minMember` pods are successfully.
@@ -107,6 +108,10 @@ const (
 
 	// PodGroupFailed means at least one of `spec.minMember` pods is failed.
 	PodGroupFailed PodGroupPhase = "Failed"
+
+	// PodGroupTimeout means `spec.minMember` pods of the pod group were not scheduled within
+	// `spec.scheduleTimeoutSeconds`; the remaining pods are no longer scheduled.
+	PodGroupTimeout PodGroupPhase = "Timeout"
 )
 
 // +genclient
@@ -136,13 +141,134 @@ type PodGroupSpec struct {
 	// will not start anyone.
 	MinMember int32 `json:"minMember,omitempty"`
 
+	// MaxMember defines the maximal number of members/tasks of an elastic pod group;
+	// once minMember members are scheduled, more members are scheduled opportunistically
+	// without waiting for each other, up to maxMember. It is no upper bound if not set.
+	// +optional
+	MaxMember *int32 `json:"maxMember,omitempty"`
+
 	// MinResources defines the minimal resource of members/tasks to run the pod group;
 	// if there's not enough resources to start all tasks, the scheduler
 	// will not start anyone.
 	MinResources *v1.ResourceList `json:"minResources,omitempty"`
 
 	// ScheduleTimeoutSeconds defines the maximal time of members/tasks to wait before run the pod group;
+	// the pod group turns to phase Timeout if `spec.minMember` pods are not scheduled within it since
+	// the pod group started scheduling, or since it was created.
 	ScheduleTimeoutSeconds *int32 `json:"scheduleTimeoutSeconds,omitempty"`
+
+	// TopologyConstraint defines the topology domain that members/tasks of the pod group are placed in.
+	// +optional
+	TopologyConstraint *TopologyConstraint `json:"topologyConstraint,omitempty"`
+
+	// PriorityClassName is the name of the PriorityClass whose value is used as the priority of all
+	// members/tasks of the pod group when sorting the scheduling queue, instead of the priorities of
+	// the individual pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Roles defines the roles of members/tasks (e.g., launcher, worker) and the minimal number of
+	// members/tasks of each role; the pod group is not started until the minimum of every role is met,
+	// in addition to minMember.
+	// +optional
+	Roles []PodGroupRole `json:"roles,omitempty"`
+
+	// BindFailurePolicy defines the compensating action when a member fails to be bound after the pod
+	// group is permitted. If not set, the pod group backs off as after any other scheduling failure.
+	// +optional
+	BindFailurePolicy BindFailurePolicy `json:"bindFailurePolicy,omitempty"`
+
+	// DependsOn defines the pod groups, in the same namespace, that must reach a required phase before
+	// members/tasks of the pod group are scheduled, e.g., parameter servers before workers.
+	// +optional
+	DependsOn []PodGroupDependency `json:"dependsOn,omitempty"`
+
+	// TTLSecondsAfterFinished limits the lifetime of a pod group that has finished, failed or timed out.
+	// The pod group is deleted once the TTL expires after it reaches the phase. If not set, the default
+	// of the controller is used.
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// FailurePolicy defines the action on the pod group when a member fails. If not set, the pod group
+	// turns to phase Failed and its remaining members are kept.
+	// +optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// MaxRestarts is the number of times that a pod group with the RestartGroup failure policy is restarted
+	// before it fails.
+	// +optional
+	MaxRestarts int32 `json:"maxRestarts,omitempty"`
+}
+
+// FailurePolicy is the action on a pod group when a member fails.
+type FailurePolicy string
+
+const (
+	// FailureIgnore keeps the pod group running when a member fails.
+	FailureIgnore FailurePolicy = "Ignore"
+
+	// FailureFailGroup turns the pod group to phase Failed, and deletes its remaining members so that
+	// they release their resources.
+	FailureFailGroup FailurePolicy = "FailGroup"
+
+	// FailureRestartGroup deletes all members of the pod group, so that their workloads recreate them and
+	// the pod group is scheduled again as a whole, up to `spec.maxRestarts` times; the pod group then
+	// fails as with FailureFailGroup.
+	FailureRestartGroup FailurePolicy = "RestartGroup"
+)
+
+// PodGroupDependency defines a pod group that a pod group depends on.
+type PodGroupDependency struct {
+	// Name is the name of the pod group depended on, in the same namespace.
+	Name string `json:"name"`
+
+	// Phase is the phase that the pod group depended on must have reached, one of Scheduled, Running and
+	// Finished. A later phase, except Failed, satisfies the dependency as well. Defaults to Running.
+	// +optional
+	Phase PodGroupPhase `json:"phase,omitempty"`
+}
+
+// BindFailurePolicy is the compensating action when a member of a permitted pod group fails to be bound.
+type BindFailurePolicy string
+
+const (
+	// BindFailureRequeue sorts the member that failed to be bound ahead of the other pods of the same
+	// priority once it is re-queued, without backing off the pod group. The members that have been bound are kept.
+	BindFailureRequeue BindFailurePolicy = "Requeue"
+
+	// BindFailureRollback re-queues the member that failed to be bound as BindFailureRequeue does, and
+	// evicts the members that have been bound or are being bound, so that the pod group is scheduled again as a whole.
+	BindFailureRollback BindFailurePolicy = "Rollback"
+)
+
+// PodGroupRole defines a role of members of a pod group.
+type PodGroupRole struct {
+	// Name is the name of the role, unique in the pod group.
+	Name string `json:"name"`
+
+	// Selector selects the members of the role among the members of the pod group.
+	// If not set, members with the label `role.scheduling.sigs.k8s.io` set to the name of the role are selected.
+	// A member belongs to the first role that selects it.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// MinMember defines the minimal number of members of the role to run the pod group.
+	MinMember int32 `json:"minMember,omitempty"`
+}
+
+// TopologyConstraint defines the topology domain (e.g., zone, rack) that members of a pod group
+// should be placed in. A topology domain is a set of nodes with the same value of a node label.
+type TopologyConstraint struct {
+	// RequiredTopologyKey is the key of a node label. All members of the pod group must be
+	// placed on nodes that have the same value of this label, which is decided by the first
+	// placed member. Nodes without the label are not feasible for the members.
+	// +optional
+	RequiredTopologyKey string `json:"requiredTopologyKey,omitempty"`
+
+	// PreferredTopologyKeys are keys of node labels. The scheduler prefers nodes in the topology
+	// domains, of each key, that are able to hold all members of the pod group.
+	// +optional
+	PreferredTopologyKeys []string `json:"preferredTopologyKeys,omitempty"`
 }
 
 // PodGroupStatus represents the current state of a pod group.
@@ -150,14 +276,20 @@ type PodGroupStatus struct {
 	// Current phase of PodGroup.
 	Phase PodGroupPhase `json:"phase,omitempty"`
 
-	// OccupiedBy marks the workload (e.g., deployment, statefulset) UID that occupy the podgroup.
-	// It is empty if not initialized.
+	// OccupiedBy marks the workloads (e.g., deployment, statefulset) that occupy the podgroup, as the
+	// sorted namespace/name of the top-level owners of its first member, joined by commas. Pods of other
+	// workloads are denied from joining the pod group. It is empty if not initialized, and released once
+	// no member is owned by the workloads.
 	OccupiedBy string `json:"occupiedBy,omitempty"`
 
 	// The number of actively running pods.
 	// +optional
 	Scheduled int32 `json:"scheduled,omitempty"`
 
+	// The number of scheduled pods beyond minMember, up to maxMember.
+	// +optional
+	OptionalScheduled int32 `json:"optionalScheduled,omitempty"`
+
 	// The number of actively running pods.
 	// +optional
 	Running int32 `json:"running,omitempty"`
@@ -170,8 +302,142 @@ type PodGroupStatus struct {
 	// +optional
 	Failed int32 `json:"failed,omitempty"`
 
-	// ScheduleStartTime of the group
+	// ScheduleStartTime of the group, which is reset to the time of the last restart by the RestartGroup
+	// failure policy.
 	ScheduleStartTime metav1.Time `json:"scheduleStartTime,omitempty"`
+
+	// CompletionTime is the time when the group reached phase Finished, Failed or Timeout.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Restarts is the number of times the group has been restarted by the RestartGroup failure policy.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// LastRestartTime is the time of the last restart of the group by the RestartGroup failure policy.
+	// Members created before are not counted as failed, as they were deleted by the restart.
+	// +optional
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
+
+	// BackoffSeconds is the current backoff of the group after it failed to be scheduled.
+	// It is zero if the group is not backing off.
+	// +optional
+	BackoffSeconds int32 `json:"backoffSeconds,omitempty"`
+
+	// ReservedNodes are the nodes reserved for the group after it waited for too long. Pods with
+	// lower priority than the group are not scheduled onto the nodes until the group is scheduled
+	// or its schedule timeout expires.
+	// +optional
+	ReservedNodes []string `json:"reservedNodes,omitempty"`
+
+	// Roles reports the numbers of pods of each role in spec.roles.
+	// +optional
+	Roles []PodGroupRoleStatus `json:"roles,omitempty"`
+
+	// Conditions explain why the pod group is, or is not, scheduled.
+	// +optional
+	Conditions []PodGroupCondition `json:"conditions,omitempty"`
+}
+
+// PodGroupConditionType is the type of a condition of a pod group.
+type PodGroupConditionType string
+
+// These are the valid condition types of podGroups.
+const (
+	// PodGroupScheduledCondition is true once `spec.minMember` pods of the pod group have been scheduled.
+	// Its reason explains what blocks the pod group while it is false.
+	PodGroupScheduledCondition PodGroupConditionType = "Scheduled"
+
+	// PodGroupBackingOffCondition is true while pods of the pod group are denied by the scheduler
+	// after the pod group failed to be scheduled.
+	PodGroupBackingOffCondition PodGroupConditionType = "BackingOff"
+)
+
+// These are the reasons of the conditions of podGroups.
+const (
+	// PodGroupNotEnoughMembersReason means fewer pods than `spec.minMember`, or than the minMember
+	// of a role, have been created.
+	PodGroupNotEnoughMembersReason = "NotEnoughMembers"
+
+	// PodGroupInsufficientResourcesReason means the free resources of the cluster cannot hold
+	// `spec.minResources` or `spec.minMember` pods of the pod group.
+	PodGroupInsufficientResourcesReason = "InsufficientResources"
+
+	// PodGroupPermitTimeoutReason means `spec.minMember` pods were not assigned nodes before the
+	// assigned pods timed out waiting for each other.
+	PodGroupPermitTimeoutReason = "PermitTimeout"
+
+	// PodGroupBindFailedReason means a member of the pod group failed to be bound after the pod group
+	// was permitted.
+	PodGroupBindFailedReason = "BindFailed"
+
+	// PodGroupDependenciesNotReadyReason means a pod group in `spec.dependsOn` has not reached its
+	// required phase.
+	PodGroupDependenciesNotReadyReason = "DependenciesNotReady"
+
+	// PodGroupDependencyCycleReason means the pod group depends on itself through `spec.dependsOn`,
+	// so it cannot be scheduled until the cycle is removed.
+	PodGroupDependencyCycleReason = "DependencyCycle"
+
+	// PodGroupDependenciesReadyReason means the pod groups in `spec.dependsOn` have reached their
+	// required phases, and the pod group is waiting to be scheduled.
+	PodGroupDependenciesReadyReason = "DependenciesReady"
+
+	// PodGroupScheduledReason means `spec.minMember` pods of the pod group have been scheduled.
+	PodGroupScheduledReason = "Scheduled"
+
+	// PodGroupScheduleTimeoutReason means `spec.minMember` pods of the pod group were not scheduled
+	// within `spec.scheduleTimeoutSeconds`.
+	PodGroupScheduleTimeoutReason = "ScheduleTimeout"
+
+	// PodGroupRestartedReason means a member of the pod group failed, and all of its members have been
+	// deleted to restart the pod group.
+	PodGroupRestartedReason = "Restarted"
+
+	// PodGroupMembersLostReason means members of a running pod group have been deleted, and fewer
+	// than `spec.minMember` pods are left.
+	PodGroupMembersLostReason = "MembersLost"
+
+	// PodGroupBackoffReason means pods of the pod group are denied until its backoff expires.
+	PodGroupBackoffReason = "Backoff"
+
+	// PodGroupBackoffResetReason means the pod group is no longer backing off.
+	PodGroupBackoffResetReason = "BackoffReset"
+)
+
+// PodGroupCondition describes the state of a pod group at a certain point.
+type PodGroupCondition struct {
+	// Type of the condition.
+	Type PodGroupConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+
+	// Last time the condition transitioned from one status, or reason, to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Human-readable message indicating details about the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PodGroupRoleStatus represents the current state of a role of a pod group.
+type PodGroupRoleStatus struct {
+	// Name is the name of the role.
+	Name string `json:"name"`
+
+	// The number of pods of the role that have been scheduled.
+	// +optional
+	Scheduled int32 `json:"scheduled,omitempty"`
+
+	// The number of actively running pods of the role.
+	// +optional
+	Running int32 `json:"running,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1beta1"
@@ -31,6 +32,7 @@ import (
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	policylisters "k8s.io/client-go/listers/policy/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
@@ -58,6 +60,12 @@ type CapacityScheduling struct {
 	pdbLister          policylisters.PodDisruptionBudgetLister
 	elasticQuotaLister externalv1alpha1.ElasticQuotaLister
 	elasticQuotaInfos  ElasticQuotaInfos
+	// podGroupLister is nil if the PodGroup CRD is disabled or not installed, and PodGroups are then only
+	// declared by the labels or annotations of pods.
+	podGroupLister externalv1alpha1.PodGroupLister
+	podLister      corelisters.PodLister
+	// resolver resolves the PodGroup of pods with the same keys as the Coscheduling plugin.
+	resolver *pluginsutil.PodGroupResolver
 }
 
 // PreFilterState computed at PreFilter and used at PostFilter or Reserve.
@@ -93,6 +101,9 @@ const (
 	// preFilterStateKey is the key in CycleState to NodeResourcesFit pre-computed data.
 	preFilterStateKey       = "PreFilter" + Name
 	ElasticQuotaSnapshotKey = "ElasticQuotaSnapshot"
+
+	// cacheSyncTimeout is how long New waits for the caches of ElasticQuotas and PodGroups to sync.
+	cacheSyncTimeout = time.Minute
 )
 
 // Name returns name of the plugin. It is used in logs, etc.
@@ -112,6 +123,7 @@ func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin
 		frameworkHandle:   handle,
 		elasticQuotaInfos: NewElasticQuotaInfos(),
 		pdbLister:         getPDBLister(handle.SharedInformerFactory()),
+		resolver:          pluginsutil.NewPodGroupResolver(args.PodGroupKeys),
 	}
 
 	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
@@ -126,6 +138,18 @@ func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin
 	schedSharedInformerFactory := schedinformer.NewSharedInformerFactory(client, 0)
 	c.elasticQuotaLister = schedSharedInformerFactory.Scheduling().V1alpha1().ElasticQuotas().Lister()
 	elasticQuotaInformer := schedSharedInformerFactory.Scheduling().V1alpha1().ElasticQuotas().Informer()
+	cacheSyncs := []cache.InformerSynced{elasticQuotaInformer.HasSynced}
+	// Without the PodGroup CRD, the pods of PodGroups declared by their labels or annotations are still
+	// admitted as a whole, and the other pods one by one.
+	if args.DisablePodGroupCRD {
+		klog.Infof("PodGroup CRD is disabled, %v admits PodGroups declared by pods only", Name)
+	} else if !podGroupsServed(client) {
+		klog.Warningf("PodGroup CRD is not installed, %v admits PodGroups declared by pods only", Name)
+	} else {
+		podGroupInformer := schedSharedInformerFactory.Scheduling().V1alpha1().PodGroups()
+		c.podGroupLister = podGroupInformer.Lister()
+		cacheSyncs = append(cacheSyncs, podGroupInformer.Informer().HasSynced)
+	}
 	elasticQuotaInformer.AddEventHandler(
 		cache.FilteringResourceEventHandler{
 			FilterFunc: func(obj interface{}) bool {
@@ -150,11 +174,15 @@ func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin
 			},
 		})
 
-	schedSharedInformerFactory.Start(nil)
-	if !cache.WaitForCacheSync(nil, elasticQuotaInformer.HasSynced) {
+	// The informers run as long as the scheduler, which does not stop plugins.
+	schedSharedInformerFactory.Start(make(chan struct{}))
+	syncCtx, cancel := context.WithTimeout(context.Background(), cacheSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), cacheSyncs...) {
 		return nil, fmt.Errorf("timed out waiting for caches to sync %v", Name)
 	}
 
+	c.podLister = handle.SharedInformerFactory().Core().V1().Pods().Lister()
 	podInformer := handle.SharedInformerFactory().Core().V1().Pods().Informer()
 	podInformer.AddEventHandler(
 		cache.FilteringResourceEventHandler{
@@ -185,6 +213,8 @@ func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin
 // PreFilter performs the following validations.
 // 1. Check if the (pod.request + eq.allocated) is less than eq.max.
 // 2. Check if the sum(eq's usage) > sum(eq's min).
+// For a pod of a PodGroup, the request of the whole PodGroup that has not been admitted yet is checked instead
+// of pod.request, so that a PodGroup is not partially admitted under the quota.
 func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) *framework.Status {
 	snapshotElasticQuota := c.snapshotElasticQuota()
 	preFilterState := computePodResourceRequest(pod)
@@ -198,12 +228,18 @@ func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.Cyc
 		return framework.NewStatus(framework.Success, "skipCapacityScheduling")
 	}
 
-	if eq.overUsed(preFilterState.Resource, eq.Max) {
-		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in Prefilter because ElasticQuota %v is more than Max", pod.Namespace, pod.Name, eq.Namespace))
+	request, pgName := c.computePodGroupRequest(pod, preFilterState.Resource, eq)
+	subject := fmt.Sprintf("Pod %v/%v", pod.Namespace, pod.Name)
+	if len(pgName) != 0 {
+		subject = fmt.Sprintf("%v of PodGroup %v", subject, pgName)
+	}
+
+	if eq.overUsed(request, eq.Max) {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("%v is rejected in Prefilter because ElasticQuota %v is more than Max", subject, eq.Namespace))
 	}
 
-	if elasticQuotaInfos.aggregatedMinOverUsedWithPod(preFilterState.Resource) {
-		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in Prefilter because total ElasticQuota used is more than min", pod.Namespace, pod.Name))
+	if elasticQuotaInfos.aggregatedMinOverUsedWithPod(request) {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("%v is rejected in Prefilter because total ElasticQuota used is more than min", subject))
 	}
 
 	return framework.NewStatus(framework.Success, "")
@@ -788,22 +824,95 @@ func getPodDisruptionBudgets(pdbLister policylisters.PodDisruptionBudgetLister)
 //
 // Result: CPU: 3, Memory: 3G
 func computePodResourceRequest(pod *v1.Pod) *PreFilterState {
-	result := &PreFilterState{}
-	for _, container := range pod.Spec.Containers {
-		result.Add(container.Resources.Requests)
+	return &PreFilterState{Resource: *pluginsutil.ComputePodResourceRequest(pod)}
+}
+
+// getPodGroup returns the PodGroup with the given name, or nil if it does not exist or the PodGroup CRD is not watched.
+func (c *CapacityScheduling) getPodGroup(namespace, name string) *v1alpha1.PodGroup {
+	if c.podGroupLister == nil {
+		return nil
+	}
+	pg, err := c.podGroupLister.PodGroups(namespace).Get(name)
+	if err != nil {
+		return nil
+	}
+	return pg
+}
+
+// computePodGroupRequest returns the request to be admitted under the ElasticQuota for a pod, and the name of its PodGroup.
+// If the pod belongs to a PodGroup that has fewer than minMember members admitted, i.e. reserved or bound, the request is
+// what the PodGroup still needs to start: spec.minResources of the PodGroup, or minMember times the request of the pod,
+// minus the requests of the admitted members, and at least the request of the pod itself. Otherwise it is the request
+// of the pod, and the name is empty.
+func (c *CapacityScheduling) computePodGroupRequest(pod *v1.Pod, podRequest framework.Resource, eq *ElasticQuotaInfo) (framework.Resource, string) {
+	pgName := c.resolver.GetPodGroupName(pod)
+	if len(pgName) == 0 {
+		return podRequest, ""
+	}
+	var minMember int32
+	var minResources *v1.ResourceList
+	if pg := c.getPodGroup(pod.Namespace, pgName); pg != nil {
+		minMember, minResources = pg.Spec.MinMember, pg.Spec.MinResources
+	} else if min, ok := pluginsutil.GetPodGroupMinAvailable(pod); ok {
+		// The PodGroup is declared by the labels of the pod, without the PodGroup CRD.
+		minMember = min
+	} else {
+		return podRequest, ""
 	}
 
-	// take max_resource(sum_pod, any_init_container)
-	for _, container := range pod.Spec.InitContainers {
-		result.SetMaxResource(container.Resources.Requests)
+	pods, err := c.podLister.Pods(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List pods of PodGroup %v/%v error %v", pod.Namespace, pgName, err)
+		return podRequest, ""
+	}
+	admitted := framework.NewResource(nil)
+	var admittedCount int32
+	for _, member := range pods {
+		key, err := framework.GetPodKey(member)
+		if err != nil || !eq.pods.Has(key) || c.resolver.GetPodGroupName(member) != pgName {
+			continue
+		}
+		admitted.Add(computePodResourceRequest(member).ResourceList())
+		admittedCount++
+	}
+	if admittedCount >= minMember {
+		return podRequest, ""
 	}
 
-	// If Overhead is being utilized, add to the total requests for the pod
-	if pod.Spec.Overhead != nil && utilfeature.DefaultFeatureGate.Enabled(kubefeatures.PodOverhead) {
-		result.Add(pod.Spec.Overhead)
+	var required *framework.Resource
+	if minResources != nil {
+		required = framework.NewResource(*minResources)
+	} else {
+		required = &framework.Resource{
+			MilliCPU:         podRequest.MilliCPU * int64(minMember),
+			Memory:           podRequest.Memory * int64(minMember),
+			EphemeralStorage: podRequest.EphemeralStorage * int64(minMember),
+		}
+		for name, value := range podRequest.ScalarResources {
+			required.SetScalar(name, value*int64(minMember))
+		}
 	}
 
-	return result
+	remaining := func(required, admitted, request int64) int64 {
+		if required-admitted > request {
+			return required - admitted
+		}
+		return request
+	}
+	request := framework.Resource{
+		MilliCPU:         remaining(required.MilliCPU, admitted.MilliCPU, podRequest.MilliCPU),
+		Memory:           remaining(required.Memory, admitted.Memory, podRequest.Memory),
+		EphemeralStorage: remaining(required.EphemeralStorage, admitted.EphemeralStorage, podRequest.EphemeralStorage),
+	}
+	for name, value := range required.ScalarResources {
+		request.SetScalar(name, remaining(value, admitted.ScalarResources[name], podRequest.ScalarResources[name]))
+	}
+	for name, value := range podRequest.ScalarResources {
+		if _, ok := request.ScalarResources[name]; !ok {
+			request.SetScalar(name, value)
+		}
+	}
+	return request, fmt.Sprintf("%v/%v", pod.Namespace, pgName)
 }
 
 // filterPodsWithPDBViolation groups the given "pods" into two groups of "violatingPods"
@@ -862,3 +971,18 @@ func filterPodsWithPDBViolation(pods []*v1.Pod, pdbs []*policy.PodDisruptionBudg
 func assignedPod(pod *v1.Pod) bool {
 	return len(pod.Spec.NodeName) != 0
 }
+
+// podGroupsServed returns whether the API server serves PodGroups, i.e. the PodGroup CRD is installed.
+func podGroupsServed(client versioned.Interface) bool {
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(v1alpha1.SchemeGroupVersion.String())
+	if err != nil {
+		klog.V(4).Infof("Cannot discover %v: %v", v1alpha1.SchemeGroupVersion, err)
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "podgroups" {
+			return true
+		}
+	}
+	return false
+}
@@ -38,6 +38,10 @@ import (
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 	imageutils "k8s.io/kubernetes/test/utils/image"
 
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	pluginsutil "sigs.k8s.io/scheduler-plugins/pkg/util"
 	testutil "sigs.k8s.io/scheduler-plugins/test/util"
 )
 
@@ -125,6 +129,7 @@ func TestPreFilter(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			cs := &CapacityScheduling{
 				elasticQuotaInfos: tt.elasticQuotas,
+				resolver:          pluginsutil.DefaultPodGroupResolver,
 			}
 
 			pods := make([]*v1.Pod, 0)
@@ -143,6 +148,118 @@ func TestPreFilter(t *testing.T) {
 	}
 }
 
+func TestPreFilterPodGroup(t *testing.T) {
+	ctx := context.Background()
+	pg3 := testutil.MakePG("pg3", "ns1", 3, nil, nil)
+	pg4 := testutil.MakePG("pg4", "ns1", 4, nil, nil)
+	pgWithMinResources := testutil.MakePG("pg-min-resources", "ns1", 2, nil,
+		&v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(1800, resource.DecimalSI)})
+	pgClient := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	for _, pg := range []*v1alpha1.PodGroup{pg3, pg4, pgWithMinResources} {
+		pgInformer.Informer().GetStore().Add(pg)
+	}
+
+	withPodGroup := func(pod *v1.Pod, pgName string) *v1.Pod {
+		pod.Labels = map[string]string{pluginsutil.PodGroupLabel: pgName}
+		return pod
+	}
+	withMinAvailable := func(pod *v1.Pod, minAvailable string) *v1.Pod {
+		pod.Labels[pluginsutil.PodGroupMinAvailableLabel] = minAvailable
+		return pod
+	}
+	tests := []struct {
+		name          string
+		pod           *v1.Pod
+		admitted      []*v1.Pod
+		noPodGroupCRD bool
+		expected      framework.Code
+	}{
+		{
+			name:     "minMember pods of the podgroup fit in max",
+			pod:      withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg3"),
+			expected: framework.Success,
+		},
+		{
+			name:     "minMember pods of the podgroup exceed max",
+			pod:      withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg4"),
+			expected: framework.Unschedulable,
+		},
+		{
+			name: "the remaining members of the podgroup fit in max",
+			pod:  withPodGroup(makePod("p2", "ns1", 500, 0, 0, 0, "p2", ""), "pg3"),
+			admitted: []*v1.Pod{
+				withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", "node1"), "pg3"),
+			},
+			expected: framework.Success,
+		},
+		{
+			name: "the pod is admitted alone once minMember members are admitted",
+			pod:  withPodGroup(makePod("p4", "ns1", 500, 0, 0, 0, "p4", ""), "pg3"),
+			admitted: []*v1.Pod{
+				withPodGroup(makePod("p1", "ns1", 300, 0, 0, 0, "p1", "node1"), "pg3"),
+				withPodGroup(makePod("p2", "ns1", 300, 0, 0, 0, "p2", "node1"), "pg3"),
+				withPodGroup(makePod("p3", "ns1", 300, 0, 0, 0, "p3", "node1"), "pg3"),
+			},
+			expected: framework.Success,
+		},
+		{
+			name:     "minResources of the podgroup exceed max",
+			pod:      withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg-min-resources"),
+			expected: framework.Unschedulable,
+		},
+		{
+			name:          "min-available pods of the podgroup declared by the pod fit in max without the PodGroup CRD",
+			pod:           withMinAvailable(withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg4"), "3"),
+			noPodGroupCRD: true,
+			expected:      framework.Success,
+		},
+		{
+			name:          "min-available pods of the podgroup declared by the pod exceed max without the PodGroup CRD",
+			pod:           withMinAvailable(withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg3"), "4"),
+			noPodGroupCRD: true,
+			expected:      framework.Unschedulable,
+		},
+		{
+			name:          "the pod is admitted alone without the PodGroup CRD and min-available",
+			pod:           withPodGroup(makePod("p1", "ns1", 500, 0, 0, 0, "p1", ""), "pg4"),
+			noPodGroupCRD: true,
+			expected:      framework.Success,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(cs, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			eq := newElasticQuotaInfo("ns1", v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(3000, resource.DecimalSI)},
+				v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(2000, resource.DecimalSI)},
+				v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(300, resource.DecimalSI)})
+			for _, pod := range append(tt.admitted, tt.pod) {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+			for _, pod := range tt.admitted {
+				if err := eq.addPodIfNotPresent(pod); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := &CapacityScheduling{
+				elasticQuotaInfos: ElasticQuotaInfos{"ns1": eq},
+				podGroupLister:    pgInformer.Lister(),
+				podLister:         podInformer.Lister(),
+				resolver:          pluginsutil.DefaultPodGroupResolver,
+			}
+			if tt.noPodGroupCRD {
+				c.podGroupLister = nil
+			}
+			if got := c.PreFilter(ctx, framework.NewCycleState(), tt.pod); got.Code() != tt.expected {
+				t.Errorf("expected %v, got %v : %v", tt.expected, got.Code(), got.Message())
+			}
+		})
+	}
+}
+
 func TestFindCandidates(t *testing.T) {
 	res := map[v1.ResourceName]string{v1.ResourceMemory: "150"}
 	tests := []struct {
@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformer "k8s.io/client-go/informers/core/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	quota "k8s.io/kubernetes/pkg/quota/v1"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+
+	schedv1alpha1 "sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	schedclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions/scheduling/v1alpha1"
+	schedlister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// ElasticQuotaController reports in status.used of ElasticQuotas the resources requested by the
+// assigned, non-terminated pods of their namespaces.
+type ElasticQuotaController struct {
+	eqQueue         workqueue.RateLimitingInterface
+	eqLister        schedlister.ElasticQuotaLister
+	podLister       corelister.PodLister
+	eqListerSynced  cache.InformerSynced
+	podListerSynced cache.InformerSynced
+	eqClient        schedclientset.Interface
+}
+
+// NewElasticQuotaController returns a new *ElasticQuotaController
+func NewElasticQuotaController(eqInformer schedinformer.ElasticQuotaInformer,
+	podInformer coreinformer.PodInformer,
+	eqClient schedclientset.Interface) *ElasticQuotaController {
+	ctrl := &ElasticQuotaController{
+		eqQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ElasticQuota-queue"),
+	}
+
+	eqInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.eqAdded,
+		UpdateFunc: ctrl.eqUpdated,
+	})
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.podAdded,
+		UpdateFunc: ctrl.podUpdated,
+		DeleteFunc: ctrl.podDeleted,
+	})
+
+	ctrl.eqLister = eqInformer.Lister()
+	ctrl.podLister = podInformer.Lister()
+	ctrl.eqListerSynced = eqInformer.Informer().HasSynced
+	ctrl.podListerSynced = podInformer.Informer().HasSynced
+	ctrl.eqClient = eqClient
+	return ctrl
+}
+
+// Run starts listening on channel events
+func (ctrl *ElasticQuotaController) Run(workers int, stopCh <-chan struct{}) {
+	defer ctrl.eqQueue.ShutDown()
+
+	klog.Info("Starting elastic quota controller")
+	defer klog.Info("Shutting elastic quota controller")
+
+	if !cache.WaitForCacheSync(stopCh, ctrl.eqListerSynced, ctrl.podListerSynced) {
+		klog.Error("Cannot sync caches")
+		return
+	}
+	klog.Info("Elastic quota controller sync finished")
+	for i := 0; i < workers; i++ {
+		go wait.Until(ctrl.sync, 0, stopCh)
+	}
+
+	<-stopCh
+}
+
+// eqAdded reacts to an ElasticQuota creation
+func (ctrl *ElasticQuotaController) eqAdded(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	ctrl.eqQueue.Add(key)
+}
+
+// eqUpdated reacts to an ElasticQuota update
+func (ctrl *ElasticQuotaController) eqUpdated(old, new interface{}) {
+	ctrl.eqAdded(new)
+}
+
+// podAdded enqueues the ElasticQuotas of the namespace of a pod
+func (ctrl *ElasticQuotaController) podAdded(obj interface{}) {
+	pod := obj.(*v1.Pod)
+	eqs, err := ctrl.eqLister.ElasticQuotas(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	for _, eq := range eqs {
+		klog.V(5).Infof("Add eq %v when pod %v changes", eq.Name, pod.Name)
+		ctrl.eqAdded(eq)
+	}
+}
+
+// podUpdated reacts to a pod update, if the pod is assigned or terminated
+func (ctrl *ElasticQuotaController) podUpdated(old, new interface{}) {
+	oldPod, newPod := old.(*v1.Pod), new.(*v1.Pod)
+	if oldPod.Spec.NodeName == newPod.Spec.NodeName && oldPod.Status.Phase == newPod.Status.Phase {
+		return
+	}
+	ctrl.podAdded(new)
+}
+
+// podDeleted reacts to a pod deletion
+func (ctrl *ElasticQuotaController) podDeleted(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		if pod, ok = tombstone.Obj.(*v1.Pod); !ok {
+			runtime.HandleError(fmt.Errorf("tombstone contained object that is not a pod %#v", obj))
+			return
+		}
+	}
+	ctrl.podAdded(pod)
+}
+
+// sync deals with one key off the queue.
+func (ctrl *ElasticQuotaController) sync() {
+	keyObj, quit := ctrl.eqQueue.Get()
+	if quit {
+		return
+	}
+	defer ctrl.eqQueue.Done(keyObj)
+
+	key := keyObj.(string)
+	namespace, eqName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	eq, err := ctrl.eqLister.ElasticQuotas(namespace).Get(eqName)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			klog.V(3).Infof("EQ %q deleted", key)
+			return
+		}
+		klog.Errorf("Error getting ElasticQuota %q: %v", key, err)
+		ctrl.eqQueue.AddRateLimited(keyObj)
+		return
+	}
+	if err := ctrl.syncHandler(context.TODO(), eq); err != nil {
+		klog.Errorf("Error syncing ElasticQuota %q: %v", key, err)
+		ctrl.eqQueue.AddRateLimited(keyObj)
+		return
+	}
+	ctrl.eqQueue.Forget(keyObj)
+}
+
+// syncHandler updates status.used of an ElasticQuota if it changes.
+func (ctrl *ElasticQuotaController) syncHandler(ctx context.Context, eq *schedv1alpha1.ElasticQuota) error {
+	pods, err := ctrl.podLister.Pods(eq.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	used := computeElasticQuotaUsed(eq, pods)
+	if quota.Equals(used, eq.Status.Used) {
+		return nil
+	}
+	eqCopy := eq.DeepCopy()
+	eqCopy.Status.Used = used
+	_, err = ctrl.eqClient.SchedulingV1alpha1().ElasticQuotas(eq.Namespace).UpdateStatus(ctx, eqCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// computeElasticQuotaUsed sums the requests of the assigned, non-terminated pods, as the scheduler charges them to the
+// ElasticQuota. Resources are reported if they are used, or limited by spec.min or spec.max of the ElasticQuota.
+func computeElasticQuotaUsed(eq *schedv1alpha1.ElasticQuota, pods []*v1.Pod) v1.ResourceList {
+	total := &framework.Resource{}
+	for _, pod := range pods {
+		if len(pod.Spec.NodeName) == 0 || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		total.Add(util.ComputePodResourceRequest(pod).ResourceList())
+	}
+	used := v1.ResourceList{}
+	for name, quantity := range total.ResourceList() {
+		_, inMin := eq.Spec.Min[name]
+		_, inMax := eq.Spec.Max[name]
+		if !quantity.IsZero() || inMin || inMax {
+			used[name] = quantity
+		}
+	}
+	return used
+}
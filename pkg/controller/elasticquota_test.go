@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	quota "k8s.io/kubernetes/pkg/quota/v1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+)
+
+func TestElasticQuotaController(t *testing.T) {
+	ctx := context.TODO()
+	cases := []struct {
+		name         string
+		pods         []*v1.Pod
+		used         v1.ResourceList
+		expectedUsed v1.ResourceList
+	}{
+		{
+			name: "assigned pods are counted",
+			pods: []*v1.Pod{
+				makeEQPod("p1", "n1", v1.PodRunning, "1", "100"),
+				makeEQPod("p2", "n1", v1.PodPending, "2", "200"),
+			},
+			expectedUsed: makeResourceList("3", "300"),
+		},
+		{
+			name: "unassigned and terminated pods are not counted",
+			pods: []*v1.Pod{
+				makeEQPod("p1", "n1", v1.PodRunning, "1", "100"),
+				makeEQPod("p2", "", v1.PodPending, "2", "200"),
+				makeEQPod("p3", "n1", v1.PodSucceeded, "2", "200"),
+				makeEQPod("p4", "n1", v1.PodFailed, "2", "200"),
+			},
+			expectedUsed: makeResourceList("1", "100"),
+		},
+		{
+			name: "init containers are counted as the scheduler does",
+			pods: []*v1.Pod{
+				func() *v1.Pod {
+					pod := makeEQPod("p1", "n1", v1.PodRunning, "1", "100")
+					pod.Spec.InitContainers = []v1.Container{{Resources: v1.ResourceRequirements{Requests: makeResourceList("3", "50")}}}
+					return pod
+				}(),
+			},
+			expectedUsed: makeResourceList("3", "100"),
+		},
+		{
+			name:         "limited resources are reported when unused",
+			used:         makeResourceList("2", "200"),
+			expectedUsed: makeResourceList("0", "0"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			eq := &v1alpha1.ElasticQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "default"},
+				Spec:       v1alpha1.ElasticQuotaSpec{Max: makeResourceList("10", "1000")},
+				Status:     v1alpha1.ElasticQuotaStatus{Used: c.used},
+			}
+			kubeClient := fake.NewSimpleClientset()
+			eqClient := pgfake.NewSimpleClientset(eq)
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			for _, pod := range c.pods {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+			eqInformerFactory := schedinformer.NewSharedInformerFactory(eqClient, 0)
+			eqInformer := eqInformerFactory.Scheduling().V1alpha1().ElasticQuotas()
+			eqInformer.Informer().GetStore().Add(eq)
+
+			ctrl := NewElasticQuotaController(eqInformer, podInformer, eqClient)
+			if err := ctrl.syncHandler(ctx, eq); err != nil {
+				t.Fatal(err)
+			}
+			got, err := eqClient.SchedulingV1alpha1().ElasticQuotas("default").Get(ctx, "eq", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !quota.Equals(got.Status.Used, c.expectedUsed) {
+				t.Errorf("expected used %v, got %v", c.expectedUsed, got.Status.Used)
+			}
+		})
+	}
+}
+
+func makeEQPod(name, nodeName string, phase v1.PodPhase, cpu, mem string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName:   nodeName,
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: makeResourceList(cpu, mem)}}},
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+}
+
+func makeResourceList(cpu, mem string) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(mem),
+	}
+}
@@ -20,8 +20,6 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"sort"
-	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -57,13 +55,22 @@ type PodGroupController struct {
 	pgListerSynced  cache.InformerSynced
 	podListerSynced cache.InformerSynced
 	pgClient        schedclientset.Interface
+	kubeClient      kubernetes.Interface
+	resolver        *util.PodGroupResolver
+	// ttlSecondsAfterFinished is the default TTL of finished pod groups, which are kept if it is nil.
+	ttlSecondsAfterFinished *int32
+	// deletePodsAfterFinished deletes the pods of a finished pod group together with it when its TTL expires.
+	deletePodsAfterFinished bool
 }
 
 // NewPodGroupController returns a new *PodGroupController
 func NewPodGroupController(client kubernetes.Interface,
 	pgInformer schedinformer.PodGroupInformer,
 	podInformer coreinformer.PodInformer,
-	pgClient schedclientset.Interface) *PodGroupController {
+	pgClient schedclientset.Interface,
+	resolver *util.PodGroupResolver,
+	ttlSecondsAfterFinished *int32,
+	deletePodsAfterFinished bool) *PodGroupController {
 	broadcaster := record.NewBroadcaster()
 	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: client.CoreV1().Events(v1.NamespaceAll)})
 
@@ -79,6 +86,7 @@ func NewPodGroupController(client kubernetes.Interface,
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ctrl.podAdded,
 		UpdateFunc: ctrl.podUpdated,
+		DeleteFunc: ctrl.podDeleted,
 	})
 
 	ctrl.pgLister = pgInformer.Lister()
@@ -86,6 +94,10 @@ func NewPodGroupController(client kubernetes.Interface,
 	ctrl.pgListerSynced = pgInformer.Informer().HasSynced
 	ctrl.podListerSynced = podInformer.Informer().HasSynced
 	ctrl.pgClient = pgClient
+	ctrl.kubeClient = client
+	ctrl.resolver = resolver
+	ctrl.ttlSecondsAfterFinished = ttlSecondsAfterFinished
+	ctrl.deletePodsAfterFinished = deletePodsAfterFinished
 	return ctrl
 }
 
@@ -116,7 +128,11 @@ func (ctrl *PodGroupController) pgAdded(obj interface{}) {
 		return
 	}
 	pg := obj.(*schedv1alpha1.PodGroup)
-	if pg.Status.Phase == schedv1alpha1.PodGroupFinished || pg.Status.Phase == schedv1alpha1.PodGroupFailed {
+	if isFinished(pg) {
+		// Finished pod groups are only synced to be deleted after their TTL.
+		if ctrl.getTTLAfterFinished(pg) != nil {
+			ctrl.pgQueue.Add(key)
+		}
 		return
 	}
 	// If startScheduleTime - createTime > 2days, do not enqueue again because pod may have been GCed
@@ -130,13 +146,32 @@ func (ctrl *PodGroupController) pgAdded(obj interface{}) {
 
 // pgUpdated reacts to a PG update
 func (ctrl *PodGroupController) pgUpdated(old, new interface{}) {
+	oldPG, newPG := old.(*schedv1alpha1.PodGroup), new.(*schedv1alpha1.PodGroup)
+	if oldPG.Status.Phase != newPG.Status.Phase {
+		ctrl.enqueueDependents(newPG)
+	}
 	ctrl.pgAdded(new)
 }
 
+// enqueueDependents enqueues the PGs that depend on a PG whose phase changes.
+func (ctrl *PodGroupController) enqueueDependents(pg *schedv1alpha1.PodGroup) {
+	pgs, err := ctrl.pgLister.PodGroups(pg.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	for _, dependent := range pgs {
+		if util.DependsOn(dependent, pg.Name) {
+			klog.V(5).Infof("Add pg %v when its dependency %v changes phase to %v", dependent.Name, pg.Name, pg.Status.Phase)
+			ctrl.pgAdded(dependent)
+		}
+	}
+}
+
 // podAdded reacts to a PG creation
 func (ctrl *PodGroupController) podAdded(obj interface{}) {
 	pod := obj.(*v1.Pod)
-	pgName := util.GetPodGroupLabel(pod)
+	pgName := ctrl.resolver.GetPodGroupName(pod)
 	if len(pgName) == 0 {
 		return
 	}
@@ -149,11 +184,32 @@ func (ctrl *PodGroupController) podAdded(obj interface{}) {
 	ctrl.pgAdded(pg)
 }
 
-// pgUpdated reacts to a PG update
+// podUpdated reacts to a pod update, enqueueing the PG it is moved out of as well
 func (ctrl *PodGroupController) podUpdated(old, new interface{}) {
+	oldPod, newPod := old.(*v1.Pod), new.(*v1.Pod)
+	if ctrl.resolver.GetPodGroupName(oldPod) != ctrl.resolver.GetPodGroupName(newPod) {
+		ctrl.podAdded(oldPod)
+	}
 	ctrl.podAdded(new)
 }
 
+// podDeleted reacts to a pod deletion, so that the status of its PG is recomputed without the pod
+func (ctrl *PodGroupController) podDeleted(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		if pod, ok = tombstone.Obj.(*v1.Pod); !ok {
+			runtime.HandleError(fmt.Errorf("tombstone contained object that is not a pod %#v", obj))
+			return
+		}
+	}
+	ctrl.podAdded(pod)
+}
+
 // syncPG deals with one key off the queue.  It returns false when it's time to quit.
 func (ctrl *PodGroupController) sync() {
 	keyObj, quit := ctrl.pgQueue.Get()
@@ -200,43 +256,90 @@ func (ctrl *PodGroupController) syncHandler(ctx context.Context, pg *schedv1alph
 		}
 	}()
 
+	if isFinished(pg) {
+		err = ctrl.cleanupFinished(ctx, key, pg)
+		return
+	}
+
 	pgCopy := pg.DeepCopy()
-	selector := labels.Set(map[string]string{util.PodGroupLabel: pgCopy.Name}).AsSelector()
-	pods, err := ctrl.podLister.List(selector)
+	pods, err := ctrl.listPodGroupPods(pgCopy)
 	if err != nil {
 		klog.Errorf("List pods for group %v failed: %v", pgCopy.Name, err)
 		return
 	}
 
+	var condition *schedv1alpha1.PodGroupCondition
 	switch pgCopy.Status.Phase {
 	case "":
 		pgCopy.Status.Phase = schedv1alpha1.PodGroupPending
 	case schedv1alpha1.PodGroupPending:
-		if len(pods) >= int(pg.Spec.MinMember) {
+		if message := missingMembers(pg, pods); len(message) != 0 {
+			condition = &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionFalse,
+				Reason:  schedv1alpha1.PodGroupNotEnoughMembersReason,
+				Message: message,
+			}
+		} else if message := util.GetDependencyCycle(pg, ctrl.pgLister); len(message) != 0 {
+			condition = &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionFalse,
+				Reason:  schedv1alpha1.PodGroupDependencyCycleReason,
+				Message: message,
+			}
+		} else if message := util.GetUnsatisfiedDependency(pg, ctrl.pgLister); len(message) != 0 {
+			condition = &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionFalse,
+				Reason:  schedv1alpha1.PodGroupDependenciesNotReadyReason,
+				Message: message,
+			}
+		} else {
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupPreScheduling
-			fillOccupiedObj(pg, pods[0])
+			if current := util.GetPodGroupCondition(&pg.Status, schedv1alpha1.PodGroupScheduledCondition); current != nil &&
+				current.Reason == schedv1alpha1.PodGroupDependenciesNotReadyReason {
+				condition = &schedv1alpha1.PodGroupCondition{
+					Type:    schedv1alpha1.PodGroupScheduledCondition,
+					Status:  v1.ConditionFalse,
+					Reason:  schedv1alpha1.PodGroupDependenciesReadyReason,
+					Message: "dependencies have reached their required phases",
+				}
+			}
 		}
 	default:
 		var (
-			running   int32 = 0
-			succeeded int32 = 0
-			failed    int32 = 0
+			running      int32 = 0
+			succeeded    int32 = 0
+			failed       int32 = 0
+			scheduled    int32 = 0
+			boundPending int32 = 0
 		)
 		if len(pods) != 0 {
 			for _, pod := range pods {
+				if len(pod.Spec.NodeName) != 0 {
+					scheduled++
+				}
 				switch pod.Status.Phase {
 				case v1.PodRunning:
 					running++
 				case v1.PodSucceeded:
 					succeeded++
 				case v1.PodFailed:
-					failed++
+					if !createdBeforeRestart(pg, pod) {
+						failed++
+					}
+				case v1.PodPending:
+					if len(pod.Spec.NodeName) != 0 {
+						boundPending++
+					}
 				}
 			}
 		}
 		pgCopy.Status.Failed = failed
 		pgCopy.Status.Succeeded = succeeded
 		pgCopy.Status.Running = running
+		pgCopy.Status.OptionalScheduled = calculateOptionalScheduled(pg, scheduled)
+		pgCopy.Status.Roles = calculateRoleStatuses(pg, pods)
 
 		if pgCopy.Status.Scheduled >= pgCopy.Spec.MinMember && pgCopy.Status.Phase == schedv1alpha1.PodGroupScheduling {
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupScheduled
@@ -245,22 +348,244 @@ func (ctrl *PodGroupController) syncHandler(ctx context.Context, pg *schedv1alph
 		if pgCopy.Status.Succeeded+pgCopy.Status.Running >= pg.Spec.MinMember && pgCopy.Status.Phase == schedv1alpha1.PodGroupScheduled {
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupRunning
 		}
+		// A running pod group whose members have been deleted is degraded until enough members run again.
+		switch pgCopy.Status.Phase {
+		case schedv1alpha1.PodGroupRunning:
+			if running+succeeded+boundPending < pg.Spec.MinMember {
+				pgCopy.Status.Phase = schedv1alpha1.PodGroupUnknown
+			}
+		case schedv1alpha1.PodGroupUnknown:
+			if running+succeeded >= pg.Spec.MinMember {
+				pgCopy.Status.Phase = schedv1alpha1.PodGroupRunning
+			}
+		}
 		// Final state of pod group
 		if pgCopy.Status.Failed != 0 && pgCopy.Status.Failed+pgCopy.Status.Running+pgCopy.Status.Succeeded >= pg.Spec.
 			MinMember {
-			pgCopy.Status.Phase = schedv1alpha1.PodGroupFailed
+			if condition, err = ctrl.executeFailurePolicy(ctx, pgCopy, pods); err != nil {
+				klog.Errorf("Execute failure policy of group %v failed: %v", pgCopy.Name, err)
+				return
+			}
 		}
 		if pgCopy.Status.Succeeded >= pg.Spec.MinMember {
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupFinished
 		}
+		if isFinished(pgCopy) {
+			now := metav1.Now()
+			pgCopy.Status.CompletionTime = &now
+		}
+		if pgCopy.Status.Phase == schedv1alpha1.PodGroupScheduled || pgCopy.Status.Phase == schedv1alpha1.PodGroupRunning {
+			condition = &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionTrue,
+				Reason:  schedv1alpha1.PodGroupScheduledReason,
+				Message: fmt.Sprintf("%v members have been scheduled", pg.Spec.MinMember),
+			}
+		} else if pgCopy.Status.Phase == schedv1alpha1.PodGroupUnknown {
+			condition = &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionFalse,
+				Reason:  schedv1alpha1.PodGroupMembersLostReason,
+				Message: fmt.Sprintf("%v of %v members are left", running+succeeded+boundPending, pg.Spec.MinMember),
+			}
+		}
 	}
 
+	fillOccupiedObj(pgCopy, pods)
+
+	// A pod group waiting on its dependencies does not time out, as it cannot start by design.
+	if condition == nil || condition.Reason != schedv1alpha1.PodGroupDependenciesNotReadyReason {
+		if remaining, ok := getScheduleTimeoutRemaining(pgCopy); ok && remaining > 0 {
+			ctrl.pgQueue.AddAfter(key, remaining)
+		} else if ok {
+			if condition, err = ctrl.timeout(ctx, pgCopy, pods); err != nil {
+				klog.Errorf("Time out group %v failed: %v", pgCopy.Name, err)
+				return
+			}
+		}
+	}
+
+	transitioned := condition != nil && util.SetPodGroupCondition(&pgCopy.Status, *condition)
 	err = ctrl.patchPodGroup(pg, pgCopy)
 	if err == nil {
 		ctrl.pgQueue.Forget(pg)
+		if transitioned {
+			eventType := v1.EventTypeNormal
+			if condition.Status != v1.ConditionTrue {
+				eventType = v1.EventTypeWarning
+			}
+			ctrl.eventRecorder.Event(pg, eventType, condition.Reason, condition.Message)
+		}
+	}
+}
+
+// cleanupFinished deletes a finished pod group, and its pods if configured, once its TTL after finished expires,
+// or requeues it until then.
+func (ctrl *PodGroupController) cleanupFinished(ctx context.Context, key string, pg *schedv1alpha1.PodGroup) error {
+	ttl := ctrl.getTTLAfterFinished(pg)
+	if ttl == nil {
+		return nil
+	}
+	if pg.Status.CompletionTime == nil {
+		// The pod group finished before its completion time was recorded.
+		pgCopy := pg.DeepCopy()
+		now := metav1.Now()
+		pgCopy.Status.CompletionTime = &now
+		return ctrl.patchPodGroup(pg, pgCopy)
+	}
+	if remaining := pg.Status.CompletionTime.Add(*ttl).Sub(time.Now()); remaining > 0 {
+		klog.V(4).Infof("PG %q will be deleted in %v", key, remaining)
+		ctrl.pgQueue.AddAfter(key, remaining)
+		return nil
 	}
+
+	if ctrl.deletePodsAfterFinished {
+		pods, err := ctrl.listPodGroupPods(pg)
+		if err != nil {
+			return err
+		}
+		if err := ctrl.deletePods(ctx, pods); err != nil {
+			return err
+		}
+	}
+	err := ctrl.pgClient.SchedulingV1alpha1().PodGroups(pg.Namespace).Delete(ctx, pg.Name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(pg.UID)),
+	})
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	klog.V(3).Infof("PG %q deleted after its TTL after finished expired", key)
+	ctrl.pgQueue.Forget(key)
+	return nil
 }
 
+// timeout turns a pod group that was not scheduled within spec.scheduleTimeoutSeconds to phase Timeout, and returns
+// the condition to set. With the FailGroup failure policy, the remaining members are deleted so that batch
+// frameworks fail fast; otherwise they are kept, and denied by the scheduler.
+func (ctrl *PodGroupController) timeout(ctx context.Context, pg *schedv1alpha1.PodGroup,
+	pods []*v1.Pod) (*schedv1alpha1.PodGroupCondition, error) {
+	if pg.Spec.FailurePolicy == schedv1alpha1.FailureFailGroup {
+		if err := ctrl.deletePods(ctx, getUnfinishedPods(pods)); err != nil {
+			return nil, err
+		}
+	}
+	now := metav1.Now()
+	pg.Status.Phase = schedv1alpha1.PodGroupTimeout
+	pg.Status.CompletionTime = &now
+	return &schedv1alpha1.PodGroupCondition{
+		Type:    schedv1alpha1.PodGroupScheduledCondition,
+		Status:  v1.ConditionFalse,
+		Reason:  schedv1alpha1.PodGroupScheduleTimeoutReason,
+		Message: fmt.Sprintf("%v members were not scheduled within %vs", pg.Spec.MinMember, *pg.Spec.ScheduleTimeoutSeconds),
+	}, nil
+}
+
+// getScheduleTimeoutRemaining returns the time left before a pod group that is not scheduled yet times out, measured
+// from status.scheduleStartTime or the creation of the pod group, and whether spec.scheduleTimeoutSeconds applies.
+func getScheduleTimeoutRemaining(pg *schedv1alpha1.PodGroup) (time.Duration, bool) {
+	if pg.Spec.ScheduleTimeoutSeconds == nil {
+		return 0, false
+	}
+	switch pg.Status.Phase {
+	case schedv1alpha1.PodGroupPending, schedv1alpha1.PodGroupPreScheduling, schedv1alpha1.PodGroupScheduling:
+	default:
+		return 0, false
+	}
+	start := pg.Status.ScheduleStartTime.Time
+	if start.IsZero() {
+		start = pg.CreationTimestamp.Time
+	}
+	return start.Add(time.Duration(*pg.Spec.ScheduleTimeoutSeconds) * time.Second).Sub(time.Now()), true
+}
+
+// executeFailurePolicy executes spec.failurePolicy of a pod group with a failed member. The status of the pod group
+// is updated in place, and the condition to set, if any, is returned. Pods are deleted before the status is patched,
+// so that a failed deletion is retried with the member failure still observed.
+func (ctrl *PodGroupController) executeFailurePolicy(ctx context.Context, pg *schedv1alpha1.PodGroup,
+	pods []*v1.Pod) (*schedv1alpha1.PodGroupCondition, error) {
+	switch pg.Spec.FailurePolicy {
+	case schedv1alpha1.FailureIgnore:
+		return nil, nil
+	case schedv1alpha1.FailureRestartGroup:
+		if pg.Status.Restarts < pg.Spec.MaxRestarts {
+			if err := ctrl.deletePods(ctx, pods); err != nil {
+				return nil, err
+			}
+			now := metav1.Now()
+			pg.Status.Restarts++
+			pg.Status.LastRestartTime = &now
+			pg.Status.Phase = schedv1alpha1.PodGroupPending
+			pg.Status.Scheduled = 0
+			pg.Status.OptionalScheduled = 0
+			pg.Status.Running = 0
+			pg.Status.Succeeded = 0
+			pg.Status.Failed = 0
+			pg.Status.Roles = nil
+			// The schedule timeout of the restarted group is measured from the restart.
+			pg.Status.ScheduleStartTime = now
+			return &schedv1alpha1.PodGroupCondition{
+				Type:    schedv1alpha1.PodGroupScheduledCondition,
+				Status:  v1.ConditionFalse,
+				Reason:  schedv1alpha1.PodGroupRestartedReason,
+				Message: fmt.Sprintf("restart %v of %v after a member failed", pg.Status.Restarts, pg.Spec.MaxRestarts),
+			}, nil
+		}
+		fallthrough
+	case schedv1alpha1.FailureFailGroup:
+		if err := ctrl.deletePods(ctx, getUnfinishedPods(pods)); err != nil {
+			return nil, err
+		}
+	}
+	pg.Status.Phase = schedv1alpha1.PodGroupFailed
+	return nil, nil
+}
+
+// getUnfinishedPods returns the pods that have neither succeeded nor failed.
+func getUnfinishedPods(pods []*v1.Pod) []*v1.Pod {
+	var unfinished []*v1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			unfinished = append(unfinished, pod)
+		}
+	}
+	return unfinished
+}
+
+// deletePods deletes pods, ignoring those that are already deleted.
+func (ctrl *PodGroupController) deletePods(ctx context.Context, pods []*v1.Pod) error {
+	for _, pod := range pods {
+		if err := ctrl.kubeClient.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil &&
+			!apierrs.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTTLAfterFinished returns the TTL of a finished pod group, or nil if it is kept. Pod groups controlled by
+// workloads are deleted together with the workloads instead.
+func (ctrl *PodGroupController) getTTLAfterFinished(pg *schedv1alpha1.PodGroup) *time.Duration {
+	if metav1.GetControllerOf(pg) != nil {
+		return nil
+	}
+	seconds := pg.Spec.TTLSecondsAfterFinished
+	if seconds == nil {
+		seconds = ctrl.ttlSecondsAfterFinished
+	}
+	if seconds == nil {
+		return nil
+	}
+	ttl := time.Duration(*seconds) * time.Second
+	return &ttl
+}
+
+// isFinished returns whether a pod group has reached phase Finished, Failed or Timeout.
+func isFinished(pg *schedv1alpha1.PodGroup) bool {
+	return pg.Status.Phase == schedv1alpha1.PodGroupFinished || pg.Status.Phase == schedv1alpha1.PodGroupFailed ||
+		pg.Status.Phase == schedv1alpha1.PodGroupTimeout
+}
+
+// patchPodGroup patches the status subresource of a pod group, which is not subject to the pod group webhooks.
 func (ctrl *PodGroupController) patchPodGroup(old, new *schedv1alpha1.PodGroup) error {
 	if !reflect.DeepEqual(old, new) {
 		patch, err := util.CreateMergePatch(old, new)
@@ -269,7 +594,7 @@ func (ctrl *PodGroupController) patchPodGroup(old, new *schedv1alpha1.PodGroup)
 		}
 
 		_, err = ctrl.pgClient.SchedulingV1alpha1().PodGroups(old.Namespace).Patch(context.TODO(), old.Name, types.MergePatchType,
-			patch, metav1.PatchOptions{})
+			patch, metav1.PatchOptions{}, "status")
 		if err != nil {
 			return err
 		}
@@ -277,16 +602,99 @@ func (ctrl *PodGroupController) patchPodGroup(old, new *schedv1alpha1.PodGroup)
 	return nil
 }
 
-func fillOccupiedObj(pg *schedv1alpha1.PodGroup, pod *v1.Pod) {
-	var refs []string
-	for _, ownerRef := range pod.OwnerReferences {
-		refs = append(refs, fmt.Sprintf("%s/%s", pod.Namespace, ownerRef.Name))
+// createdBeforeRestart returns whether a pod was created before the last restart of its pod group, and thus
+// was deleted by the restart even if the deletion is not observed yet.
+func createdBeforeRestart(pg *schedv1alpha1.PodGroup, pod *v1.Pod) bool {
+	return pg.Status.LastRestartTime != nil && pod.CreationTimestamp.Before(pg.Status.LastRestartTime)
+}
+
+// listPodGroupPods lists the pods that belong to the pod group. Terminating pods are left out, as they are
+// no longer members, and are being replaced by their workloads.
+func (ctrl *PodGroupController) listPodGroupPods(pg *schedv1alpha1.PodGroup) ([]*v1.Pod, error) {
+	pods, err := ctrl.podLister.Pods(pg.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
 	}
-	if len(pg.Status.OccupiedBy) == 0 {
-		return
+	var members []*v1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && ctrl.resolver.GetPodGroupName(pod) == pg.Name {
+			members = append(members, pod)
+		}
+	}
+	return members, nil
+}
+
+// missingMembers returns a message describing the members that are missing for the pod group to be scheduled,
+// or an empty string if enough pods, of every role, have been created.
+func missingMembers(pg *schedv1alpha1.PodGroup, pods []*v1.Pod) string {
+	if len(pods) < int(pg.Spec.MinMember) {
+		return fmt.Sprintf("%v of %v members have been created", len(pods), pg.Spec.MinMember)
+	}
+	counts := util.CountPodsByRole(pg, pods)
+	for _, role := range pg.Spec.Roles {
+		if counts[role.Name] < role.MinMember {
+			return fmt.Sprintf("%v of %v members of role %v have been created", counts[role.Name], role.MinMember, role.Name)
+		}
+	}
+	return ""
+}
+
+// calculateOptionalScheduled returns the number of scheduled pods beyond minMember, up to maxMember.
+func calculateOptionalScheduled(pg *schedv1alpha1.PodGroup, scheduled int32) int32 {
+	optional := scheduled - pg.Spec.MinMember
+	if pg.Spec.MaxMember != nil && optional > *pg.Spec.MaxMember-pg.Spec.MinMember {
+		optional = *pg.Spec.MaxMember - pg.Spec.MinMember
 	}
-	if len(refs) != 0 {
-		sort.Strings(refs)
-		pg.Status.OccupiedBy = strings.Join(refs, ",")
+	if optional < 0 {
+		return 0
+	}
+	return optional
+}
+
+// calculateRoleStatuses returns the numbers of scheduled and running pods of each role of the pod group.
+func calculateRoleStatuses(pg *schedv1alpha1.PodGroup, pods []*v1.Pod) []schedv1alpha1.PodGroupRoleStatus {
+	if len(pg.Spec.Roles) == 0 {
+		return nil
+	}
+	statuses := make([]schedv1alpha1.PodGroupRoleStatus, len(pg.Spec.Roles))
+	index := make(map[string]int, len(pg.Spec.Roles))
+	for i, role := range pg.Spec.Roles {
+		statuses[i].Name = role.Name
+		index[role.Name] = i
+	}
+	for _, pod := range pods {
+		i, ok := index[util.GetPodRole(pg, pod)]
+		if !ok {
+			continue
+		}
+		if len(pod.Spec.NodeName) != 0 {
+			statuses[i].Scheduled++
+		}
+		if pod.Status.Phase == v1.PodRunning {
+			statuses[i].Running++
+		}
+	}
+	return statuses
+}
+
+// fillOccupiedObj records the owners of the first created member of a pod group that has owners in
+// status.occupiedBy, so that pods of other workloads are denied from joining the pod group. The pod group
+// is released once none of its members is owned by the recorded owners, e.g., after their workload was
+// deleted, and claimed by the owners of the remaining members if any.
+func fillOccupiedObj(pg *schedv1alpha1.PodGroup, pods []*v1.Pod) {
+	var first *v1.Pod
+	occupiedBy := ""
+	for _, pod := range pods {
+		owners := util.GetOccupiedBy(pod)
+		if len(owners) == 0 {
+			continue
+		}
+		if owners == pg.Status.OccupiedBy {
+			return
+		}
+		if first == nil || pod.CreationTimestamp.Before(&first.CreationTimestamp) {
+			first, occupiedBy = pod, owners
+		}
 	}
+	pg.Status.OccupiedBy = occupiedBy
 }
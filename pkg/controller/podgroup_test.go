@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/kubernetes/pkg/controller"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 
@@ -131,7 +133,7 @@ func Test_Run(t *testing.T) {
 			pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
 			podInformer := informerFactory.Core().V1().Pods()
 			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
-			ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient)
+			ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
 
 			pgInformerFactory.Start(ctx.Done())
 			informerFactory.Start(ctx.Done())
@@ -161,6 +163,602 @@ func Test_Run(t *testing.T) {
 
 }
 
+func Test_calculateOptionalScheduled(t *testing.T) {
+	maxMember := int32(4)
+	cases := []struct {
+		name      string
+		maxMember *int32
+		scheduled int32
+		expected  int32
+	}{
+		{
+			name:      "less than min member",
+			scheduled: 1,
+			expected:  0,
+		},
+		{
+			name:      "more than min member without max member",
+			scheduled: 5,
+			expected:  3,
+		},
+		{
+			name:      "more than min member within max member",
+			maxMember: &maxMember,
+			scheduled: 3,
+			expected:  1,
+		},
+		{
+			name:      "more than max member",
+			maxMember: &maxMember,
+			scheduled: 5,
+			expected:  2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pg := makePG("pg", 2, v1alpha1.PodGroupRunning, nil)
+			pg.Spec.MaxMember = c.maxMember
+			if got := calculateOptionalScheduled(pg, c.scheduled); got != c.expected {
+				t.Errorf("want %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func Test_calculateRoleStatuses(t *testing.T) {
+	pg := makePG("pg", 2, v1alpha1.PodGroupRunning, nil)
+	pg.Spec.Roles = []v1alpha1.PodGroupRole{
+		{Name: "launcher", MinMember: 1},
+		{Name: "worker", MinMember: 1, Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "worker"}}},
+	}
+	pods := makePods([]string{"launcher", "worker1", "worker2", "other"}, "pg", v1.PodRunning)
+	pods[0].Labels[util.RoleLabel] = "launcher"
+	pods[0].Spec.NodeName = "node1"
+	pods[1].Labels["app"] = "worker"
+	pods[1].Spec.NodeName = "node1"
+	pods[2].Labels["app"] = "worker"
+	pods[2].Status.Phase = v1.PodPending
+
+	expected := []v1alpha1.PodGroupRoleStatus{
+		{Name: "launcher", Scheduled: 1, Running: 1},
+		{Name: "worker", Scheduled: 1, Running: 1},
+	}
+	if got := calculateRoleStatuses(pg, pods); !reflect.DeepEqual(got, expected) {
+		t.Errorf("want %v, got %v", expected, got)
+	}
+}
+
+func Test_missingMembers(t *testing.T) {
+	withRoles := makePG("pg", 2, v1alpha1.PodGroupPending, nil)
+	withRoles.Spec.Roles = []v1alpha1.PodGroupRole{{Name: "launcher", MinMember: 1}, {Name: "worker", MinMember: 1}}
+	workers := makePods([]string{"worker1", "worker2"}, "pg", v1.PodPending)
+	for _, pod := range workers {
+		pod.Labels[util.RoleLabel] = "worker"
+	}
+	cases := []struct {
+		name     string
+		pg       *v1alpha1.PodGroup
+		pods     []*v1.Pod
+		expected string
+	}{
+		{
+			name:     "fewer pods than min member",
+			pg:       makePG("pg", 2, v1alpha1.PodGroupPending, nil),
+			pods:     makePods([]string{"pod1"}, "pg", v1.PodPending),
+			expected: "1 of 2 members have been created",
+		},
+		{
+			name: "enough pods",
+			pg:   makePG("pg", 2, v1alpha1.PodGroupPending, nil),
+			pods: makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending),
+		},
+		{
+			name:     "fewer pods of a role than its min member",
+			pg:       withRoles,
+			pods:     workers,
+			expected: "0 of 1 members of role launcher have been created",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := missingMembers(c.pg, c.pods); got != c.expected {
+				t.Errorf("want %q, got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+func Test_fillOccupiedObj(t *testing.T) {
+	pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending)
+	pods[0].CreationTimestamp = metav1.NewTime(time.Now())
+	pods[0].OwnerReferences = []metav1.OwnerReference{{Name: "job-b"}}
+	pods[1].CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+	pods[1].OwnerReferences = []metav1.OwnerReference{{Name: "job-a"}, {Name: "mpijob-a"}}
+
+	pg := makePG("pg", 2, v1alpha1.PodGroupPending, nil)
+	pg.Status.OccupiedBy = ""
+	fillOccupiedObj(pg, pods)
+	if expected := "default/job-a,default/mpijob-a"; pg.Status.OccupiedBy != expected {
+		t.Errorf("want occupied by %q, got %q", expected, pg.Status.OccupiedBy)
+	}
+
+	// The pod group stays occupied by its first owners while they own a member.
+	pods[0].CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	fillOccupiedObj(pg, pods)
+	if expected := "default/job-a,default/mpijob-a"; pg.Status.OccupiedBy != expected {
+		t.Errorf("want occupied by %q, got %q", expected, pg.Status.OccupiedBy)
+	}
+
+	// The pod group is claimed by the remaining owners once the first owners are gone.
+	pods[1].OwnerReferences = nil
+	fillOccupiedObj(pg, pods)
+	if expected := "default/job-b"; pg.Status.OccupiedBy != expected {
+		t.Errorf("want occupied by %q, got %q", expected, pg.Status.OccupiedBy)
+	}
+
+	// The pod group is released once no member has owners.
+	fillOccupiedObj(pg, pods[1:])
+	if pg.Status.OccupiedBy != "" {
+		t.Errorf("want the pod group released, got occupied by %q", pg.Status.OccupiedBy)
+	}
+}
+
+func Test_dependsOn(t *testing.T) {
+	ctx := context.TODO()
+	ps := makePG("ps", 1, v1alpha1.PodGroupScheduled, nil)
+	worker := makePG("worker", 2, v1alpha1.PodGroupPending, nil)
+	worker.Spec.DependsOn = []v1alpha1.PodGroupDependency{{Name: "ps"}}
+	pods := makePods([]string{"worker1", "worker2"}, "worker", v1.PodPending)
+	kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+	pgClient := pgfake.NewSimpleClientset(ps, worker)
+
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+	pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+	podInformer := informerFactory.Core().V1().Pods()
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
+	for _, pod := range pods {
+		podInformer.Informer().GetStore().Add(pod)
+	}
+	pgInformer.Informer().GetStore().Add(ps)
+	pgInformer.Informer().GetStore().Add(worker)
+
+	sync := func(expectedPhase v1alpha1.PodGroupPhase, expectedReason string) {
+		t.Helper()
+		pg, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "worker", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctrl.syncHandler(ctx, pg)
+		if pg, err = pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "worker", metav1.GetOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		condition := util.GetPodGroupCondition(&pg.Status, v1alpha1.PodGroupScheduledCondition)
+		if pg.Status.Phase != expectedPhase || condition == nil || condition.Reason != expectedReason {
+			t.Errorf("want phase %v with reason %v, got phase %v with condition %+v", expectedPhase, expectedReason,
+				pg.Status.Phase, condition)
+		}
+		pgInformer.Informer().GetStore().Update(pg)
+	}
+
+	// The worker pg is kept pending until the ps pg is running.
+	sync(v1alpha1.PodGroupPending, v1alpha1.PodGroupDependenciesNotReadyReason)
+
+	running := ps.DeepCopy()
+	running.Status.Phase = v1alpha1.PodGroupRunning
+	pgInformer.Informer().GetStore().Update(running)
+	ctrl.pgUpdated(ps, running)
+	if ctrl.pgQueue.Len() != 2 {
+		t.Errorf("want the ps and worker pgs enqueued, got %v keys", ctrl.pgQueue.Len())
+	}
+
+	sync(v1alpha1.PodGroupPreScheduling, v1alpha1.PodGroupDependenciesReadyReason)
+
+	// Pod groups that depend on each other are reported instead of waiting forever.
+	cycle := makePG("cycle", 1, v1alpha1.PodGroupPending, nil)
+	cycle.Spec.DependsOn = []v1alpha1.PodGroupDependency{{Name: "worker"}}
+	pgInformer.Informer().GetStore().Add(cycle)
+	pending, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "worker", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending.Status.Phase = v1alpha1.PodGroupPending
+	pending.Spec.DependsOn = append(pending.Spec.DependsOn, v1alpha1.PodGroupDependency{Name: "cycle"})
+	if _, err := pgClient.SchedulingV1alpha1().PodGroups("default").Update(ctx, pending, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sync(v1alpha1.PodGroupPending, v1alpha1.PodGroupDependencyCycleReason)
+}
+
+func Test_membersLost(t *testing.T) {
+	ctx := context.TODO()
+	pg := makePG("pg", 2, v1alpha1.PodGroupRunning, nil)
+	pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning)
+	kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+	pgClient := pgfake.NewSimpleClientset(pg)
+
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+	pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+	podInformer := informerFactory.Core().V1().Pods()
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
+	podInformer.Informer().GetStore().Add(pods[0])
+	pgInformer.Informer().GetStore().Add(pg)
+
+	// The deleted pod is only known from a tombstone.
+	ctrl.podDeleted(cache.DeletedFinalStateUnknown{Key: "default/pod2", Obj: pods[1]})
+	if ctrl.pgQueue.Len() != 1 {
+		t.Errorf("want the pg enqueued, got %v keys", ctrl.pgQueue.Len())
+	}
+
+	sync := func(expectedPhase v1alpha1.PodGroupPhase, expectedReason string) {
+		t.Helper()
+		pg, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctrl.syncHandler(ctx, pg)
+		if pg, err = pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		condition := util.GetPodGroupCondition(&pg.Status, v1alpha1.PodGroupScheduledCondition)
+		if pg.Status.Phase != expectedPhase || condition == nil || condition.Reason != expectedReason {
+			t.Errorf("want phase %v with reason %v, got phase %v with condition %+v", expectedPhase, expectedReason,
+				pg.Status.Phase, condition)
+		}
+	}
+	sync(v1alpha1.PodGroupUnknown, v1alpha1.PodGroupMembersLostReason)
+
+	// The pod group runs again once the workload recreates the pod.
+	podInformer.Informer().GetStore().Add(pods[1])
+	sync(v1alpha1.PodGroupRunning, v1alpha1.PodGroupScheduledReason)
+}
+
+func Test_failurePolicy(t *testing.T) {
+	ctx := context.TODO()
+	cases := []struct {
+		name           string
+		policy         v1alpha1.FailurePolicy
+		restarts       int32
+		restartedAfter bool
+		terminating    bool
+		expectedPhase  v1alpha1.PodGroupPhase
+		expectRestarts int32
+		expectPodsLeft int
+	}{
+		{
+			name:           "no policy",
+			expectedPhase:  v1alpha1.PodGroupFailed,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "ignore",
+			policy:         v1alpha1.FailureIgnore,
+			expectedPhase:  v1alpha1.PodGroupUnknown,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "fail group",
+			policy:         v1alpha1.FailureFailGroup,
+			expectedPhase:  v1alpha1.PodGroupFailed,
+			expectPodsLeft: 1,
+		},
+		{
+			name:           "restart group",
+			policy:         v1alpha1.FailureRestartGroup,
+			expectedPhase:  v1alpha1.PodGroupPending,
+			expectRestarts: 1,
+		},
+		{
+			name:           "restart group, max restarts reached",
+			policy:         v1alpha1.FailureRestartGroup,
+			restarts:       1,
+			expectedPhase:  v1alpha1.PodGroupFailed,
+			expectRestarts: 1,
+			expectPodsLeft: 1,
+		},
+		{
+			name:           "restart group, member failed before the last restart",
+			policy:         v1alpha1.FailureRestartGroup,
+			restartedAfter: true,
+			expectedPhase:  v1alpha1.PodGroupUnknown,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "restart group, failed member terminating",
+			policy:         v1alpha1.FailureRestartGroup,
+			terminating:    true,
+			expectedPhase:  v1alpha1.PodGroupUnknown,
+			expectPodsLeft: 2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pg := makePG("pg", 2, v1alpha1.PodGroupRunning, nil)
+			pg.Spec.FailurePolicy = c.policy
+			pg.Spec.MaxRestarts = 1
+			pg.Status.Restarts = c.restarts
+			pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning)
+			pods[0].Status.Phase = v1.PodFailed
+			if c.restartedAfter {
+				pg.Status.LastRestartTime = &metav1.Time{Time: time.Now()}
+			}
+			if c.terminating {
+				pods[0].DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+			pgClient := pgfake.NewSimpleClientset(pg)
+
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+			pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+			podInformer := informerFactory.Core().V1().Pods()
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+			ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
+			for _, pod := range pods {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+			pgInformer.Informer().GetStore().Add(pg)
+
+			ctrl.syncHandler(ctx, pg)
+			got, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != c.expectedPhase || got.Status.Restarts != c.expectRestarts {
+				t.Errorf("want phase %v with %v restarts, got phase %v with %v restarts", c.expectedPhase, c.expectRestarts,
+					got.Status.Phase, got.Status.Restarts)
+			}
+			podList, err := kubeClient.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(podList.Items) != c.expectPodsLeft {
+				t.Errorf("want %v pods left, got %v", c.expectPodsLeft, len(podList.Items))
+			}
+		})
+	}
+}
+
+func Test_scheduleTimeout(t *testing.T) {
+	ctx := context.TODO()
+	timeout := int32(60)
+	createTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	cases := []struct {
+		name           string
+		phase          v1alpha1.PodGroupPhase
+		startTime      metav1.Time
+		dependsOn      []v1alpha1.PodGroupDependency
+		policy         v1alpha1.FailurePolicy
+		expectedPhase  v1alpha1.PodGroupPhase
+		expectPodsLeft int
+	}{
+		{
+			name:           "scheduling group timed out since it started scheduling",
+			phase:          v1alpha1.PodGroupScheduling,
+			startTime:      createTime,
+			expectedPhase:  v1alpha1.PodGroupTimeout,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "scheduling group within the timeout",
+			phase:          v1alpha1.PodGroupScheduling,
+			startTime:      metav1.Now(),
+			expectedPhase:  v1alpha1.PodGroupScheduling,
+			expectPodsLeft: 2,
+		},
+		{
+			name:          "pending group timed out since it was created, with its pods deleted",
+			phase:         v1alpha1.PodGroupPending,
+			policy:        v1alpha1.FailureFailGroup,
+			expectedPhase: v1alpha1.PodGroupTimeout,
+		},
+		{
+			name:           "pending group waiting on its dependencies",
+			phase:          v1alpha1.PodGroupPending,
+			dependsOn:      []v1alpha1.PodGroupDependency{{Name: "ps"}},
+			expectedPhase:  v1alpha1.PodGroupPending,
+			expectPodsLeft: 2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pg := makePG("pg", 2, c.phase, &createTime)
+			pg.Spec.ScheduleTimeoutSeconds = &timeout
+			pg.Spec.DependsOn = c.dependsOn
+			pg.Spec.FailurePolicy = c.policy
+			pg.Status.Scheduled = 1
+			pg.Status.ScheduleStartTime = c.startTime
+			pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending)
+			kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+			pgClient := pgfake.NewSimpleClientset(pg)
+
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+			pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+			podInformer := informerFactory.Core().V1().Pods()
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+			ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
+			for _, pod := range pods {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+			pgInformer.Informer().GetStore().Add(pg)
+
+			ctrl.syncHandler(ctx, pg)
+			got, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != c.expectedPhase {
+				t.Errorf("want phase %v, got %v", c.expectedPhase, got.Status.Phase)
+			}
+			if condition := util.GetPodGroupCondition(&got.Status, v1alpha1.PodGroupScheduledCondition); (c.expectedPhase ==
+				v1alpha1.PodGroupTimeout) != (condition != nil && condition.Reason == v1alpha1.PodGroupScheduleTimeoutReason) {
+				t.Errorf("want the ScheduleTimeout reason only if timed out, got %+v", condition)
+			}
+			podList, err := kubeClient.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(podList.Items) != c.expectPodsLeft {
+				t.Errorf("want %v pods left, got %v", c.expectPodsLeft, len(podList.Items))
+			}
+		})
+	}
+}
+
+func Test_restartWithScheduleTimeout(t *testing.T) {
+	ctx := context.TODO()
+	timeout := int32(60)
+	createTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	pg := makePG("pg", 2, v1alpha1.PodGroupRunning, &createTime)
+	pg.Spec.ScheduleTimeoutSeconds = &timeout
+	pg.Spec.FailurePolicy = v1alpha1.FailureRestartGroup
+	pg.Spec.MaxRestarts = 1
+	pg.Status.ScheduleStartTime = createTime
+	pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning)
+	pods[0].Status.Phase = v1.PodFailed
+	kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+	pgClient := pgfake.NewSimpleClientset(pg)
+
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+	pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+	podInformer := informerFactory.Core().V1().Pods()
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver, nil, false)
+	for _, pod := range pods {
+		podInformer.Informer().GetStore().Add(pod)
+	}
+	pgInformer.Informer().GetStore().Add(pg)
+
+	ctrl.syncHandler(ctx, pg)
+	got, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != v1alpha1.PodGroupPending || got.Status.Restarts != 1 {
+		t.Fatalf("want phase %v with 1 restart, got phase %v with %v restarts", v1alpha1.PodGroupPending,
+			got.Status.Phase, got.Status.Restarts)
+	}
+	if condition := util.GetPodGroupCondition(&got.Status, v1alpha1.PodGroupScheduledCondition); condition == nil ||
+		condition.Reason != v1alpha1.PodGroupRestartedReason {
+		t.Errorf("want the Restarted reason, got %+v", condition)
+	}
+
+	// The members recreated by their workloads are scheduled again within the timeout from the restart.
+	for _, pod := range pods {
+		podInformer.Informer().GetStore().Delete(pod)
+	}
+	pods = makePods([]string{"pod3", "pod4"}, "pg", v1.PodPending)
+	for _, pod := range pods {
+		podInformer.Informer().GetStore().Add(pod)
+	}
+	pgInformer.Informer().GetStore().Update(got)
+	ctrl.syncHandler(ctx, got)
+	got, err = pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase == v1alpha1.PodGroupTimeout {
+		t.Errorf("want the restarted group not to time out, got phase %v", got.Status.Phase)
+	}
+}
+
+func Test_cleanupFinished(t *testing.T) {
+	ctx := context.TODO()
+	ttl := int32(60)
+	expired := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	notExpired := metav1.NewTime(time.Now().Add(-30 * time.Second))
+	cases := []struct {
+		name            string
+		ttl             *int32
+		pgTTL           *int32
+		completionTime  *metav1.Time
+		controlled      bool
+		deletePods      bool
+		expectPGDeleted bool
+		expectPodsLeft  int
+	}{
+		{
+			name:           "no ttl",
+			completionTime: &expired,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "ttl not expired",
+			ttl:            &ttl,
+			completionTime: &notExpired,
+			expectPodsLeft: 2,
+		},
+		{
+			name:            "default ttl expired",
+			ttl:             &ttl,
+			completionTime:  &expired,
+			expectPGDeleted: true,
+			expectPodsLeft:  2,
+		},
+		{
+			name:            "ttl of the pod group expired, with its pods",
+			pgTTL:           &ttl,
+			completionTime:  &expired,
+			deletePods:      true,
+			expectPGDeleted: true,
+		},
+		{
+			name:           "completion time not recorded",
+			ttl:            &ttl,
+			expectPodsLeft: 2,
+		},
+		{
+			name:           "pod group controlled by a workload",
+			ttl:            &ttl,
+			completionTime: &expired,
+			controlled:     true,
+			expectPodsLeft: 2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pg := makePG("pg", 2, v1alpha1.PodGroupFinished, nil)
+			pg.Spec.TTLSecondsAfterFinished = c.pgTTL
+			pg.Status.CompletionTime = c.completionTime
+			if c.controlled {
+				isController := true
+				pg.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "job",
+					Controller: &isController}}
+			}
+			pods := makePods([]string{"pod1", "pod2"}, "pg", v1.PodSucceeded)
+			kubeClient := fake.NewSimpleClientset(pods[0], pods[1])
+			pgClient := pgfake.NewSimpleClientset(pg)
+
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, controller.NoResyncPeriodFunc())
+			pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, controller.NoResyncPeriodFunc())
+			podInformer := informerFactory.Core().V1().Pods()
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+			ctrl := NewPodGroupController(kubeClient, pgInformer, podInformer, pgClient, util.DefaultPodGroupResolver,
+				c.ttl, c.deletePods)
+			for _, pod := range pods {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+			pgInformer.Informer().GetStore().Add(pg)
+
+			ctrl.syncHandler(ctx, pg)
+			got, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "pg", metav1.GetOptions{})
+			if deleted := err != nil; deleted != c.expectPGDeleted {
+				t.Errorf("want pg deleted: %v, got %v", c.expectPGDeleted, err)
+			}
+			if c.completionTime == nil && got != nil && got.Status.CompletionTime == nil {
+				t.Errorf("want the completion time recorded")
+			}
+			podList, err := kubeClient.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(podList.Items) != c.expectPodsLeft {
+				t.Errorf("want %v pods left, got %v", c.expectPodsLeft, len(podList.Items))
+			}
+		})
+	}
+}
+
 func makePods(podNames []string, pgName string, phase v1.PodPhase) []*v1.Pod {
 	pds := make([]*v1.Pod, 0)
 	for _, name := range podNames {
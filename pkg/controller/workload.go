@@ -0,0 +1,327 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	coreinformer "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	schedv1alpha1 "sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	schedclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions/scheduling/v1alpha1"
+	schedlister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// DefaultWorkloadResources are the workloads that PodGroups are created for by default.
+var DefaultWorkloadResources = []schema.GroupVersionResource{
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "replicasets"},
+}
+
+// workloadKey is the key of a workload in the queue of the WorkloadController.
+type workloadKey struct {
+	resource schema.GroupVersionResource
+	key      string
+}
+
+// WorkloadController creates PodGroups for workloads annotated with the minimum number of members, and makes
+// the workloads own the PodGroups. The pod templates of the workloads are left unchanged: their pods are labeled
+// with the PodGroups by the pod mutating webhook as they are created, or by the controller if they were created
+// before the PodGroups.
+type WorkloadController struct {
+	eventRecorder  record.EventRecorder
+	queue          workqueue.RateLimitingInterface
+	listers        map[schema.GroupVersionResource]cache.GenericLister
+	pgLister       schedlister.PodGroupLister
+	podLister      corelister.PodLister
+	informerSynced []cache.InformerSynced
+	kubeClient     kubernetes.Interface
+	pgClient       schedclientset.Interface
+	resolver       *util.PodGroupResolver
+}
+
+// NewWorkloadController returns a new *WorkloadController watching the given workload resources.
+// podInformer must watch all the pods, not only the members of PodGroups.
+func NewWorkloadController(client kubernetes.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory,
+	pgInformer schedinformer.PodGroupInformer,
+	podInformer coreinformer.PodInformer,
+	pgClient schedclientset.Interface,
+	resolver *util.PodGroupResolver,
+	resources []schema.GroupVersionResource) *WorkloadController {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: client.CoreV1().Events(v1.NamespaceAll)})
+
+	ctrl := &WorkloadController{
+		eventRecorder:  broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "Coscheduling"}),
+		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Workload-queue"),
+		listers:        make(map[schema.GroupVersionResource]cache.GenericLister, len(resources)),
+		pgLister:       pgInformer.Lister(),
+		podLister:      podInformer.Lister(),
+		informerSynced: []cache.InformerSynced{pgInformer.Informer().HasSynced, podInformer.Informer().HasSynced},
+		kubeClient:     client,
+		pgClient:       pgClient,
+		resolver:       resolver,
+	}
+
+	for _, resource := range resources {
+		informer := informerFactory.ForResource(resource)
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    ctrl.workloadAdded(resource),
+			UpdateFunc: func(old, new interface{}) { ctrl.workloadAdded(resource)(new) },
+		})
+		ctrl.listers[resource] = informer.Lister()
+		ctrl.informerSynced = append(ctrl.informerSynced, informer.Informer().HasSynced)
+	}
+	pgInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: ctrl.pgDeleted,
+	})
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: ctrl.podAdded,
+	})
+	return ctrl
+}
+
+// Run starts listening on channel events
+func (ctrl *WorkloadController) Run(workers int, stopCh <-chan struct{}) {
+	defer ctrl.queue.ShutDown()
+
+	klog.Info("Starting workload controller")
+	defer klog.Info("Shutting workload controller")
+
+	if !cache.WaitForCacheSync(stopCh, ctrl.informerSynced...) {
+		klog.Error("Cannot sync caches")
+		return
+	}
+	klog.Info("Workload controller sync finished")
+	for i := 0; i < workers; i++ {
+		go wait.Until(ctrl.sync, 0, stopCh)
+	}
+
+	<-stopCh
+}
+
+// workloadAdded returns the handler that enqueues the annotated workloads of a resource.
+func (ctrl *WorkloadController) workloadAdded(resource schema.GroupVersionResource) func(obj interface{}) {
+	return func(obj interface{}) {
+		workload, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		if _, ok := workload.GetAnnotations()[util.PodGroupMinAvailableLabel]; !ok {
+			return
+		}
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		ctrl.queue.Add(workloadKey{resource: resource, key: key})
+	}
+}
+
+// pgDeleted re-creates a PodGroup deleted while its owner workload still exists.
+func (ctrl *WorkloadController) pgDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pg, ok := obj.(*schedv1alpha1.PodGroup)
+	if !ok {
+		return
+	}
+	ctrl.enqueueOwner(pg.Namespace, metav1.GetControllerOf(pg))
+}
+
+// podAdded enqueues the workload of a pod without PodGroup, which may have been created before the PodGroup
+// of the workload was cached by the pod mutating webhook.
+func (ctrl *WorkloadController) podAdded(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || len(ctrl.resolver.GetPodGroupName(pod)) != 0 {
+		return
+	}
+	ctrl.enqueueOwner(pod.Namespace, metav1.GetControllerOf(pod))
+}
+
+// enqueueOwner enqueues the workload of the given owner, if it is a watched workload.
+func (ctrl *WorkloadController) enqueueOwner(namespace string, owner *metav1.OwnerReference) {
+	if owner == nil {
+		return
+	}
+	for resource, lister := range ctrl.listers {
+		workload, err := lister.ByNamespace(namespace).Get(owner.Name)
+		if err != nil {
+			continue
+		}
+		if accessor, ok := workload.(*unstructured.Unstructured); ok && accessor.GetUID() == owner.UID {
+			ctrl.workloadAdded(resource)(workload)
+			return
+		}
+	}
+}
+
+// sync deals with one key off the queue.
+func (ctrl *WorkloadController) sync() {
+	keyObj, quit := ctrl.queue.Get()
+	if quit {
+		return
+	}
+	defer ctrl.queue.Done(keyObj)
+
+	key := keyObj.(workloadKey)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key.key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	obj, err := ctrl.listers[key.resource].ByNamespace(namespace).Get(name)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			// The workload was deleted in the meantime, its PodGroup is garbage collected.
+			klog.V(3).Infof("Workload %v %q deleted", key.resource.Resource, key.key)
+			return
+		}
+		klog.Errorf("Error getting workload %v %q: %v", key.resource.Resource, key.key, err)
+		ctrl.queue.AddRateLimited(keyObj)
+		return
+	}
+	if err := ctrl.syncHandler(context.TODO(), obj.(*unstructured.Unstructured)); err != nil {
+		klog.Errorf("Error syncing workload %v %q: %v", key.resource.Resource, key.key, err)
+		ctrl.queue.AddRateLimited(keyObj)
+		return
+	}
+	ctrl.queue.Forget(keyObj)
+}
+
+// syncHandler creates or updates the PodGroup of a workload, and labels the pods of the workload with it.
+func (ctrl *WorkloadController) syncHandler(ctx context.Context, workload *unstructured.Unstructured) error {
+	if workload.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	minMember, err := getWorkloadMinMember(workload)
+	if err != nil {
+		ctrl.eventRecorder.Event(workload, v1.EventTypeWarning, "InvalidMinAvailable", err.Error())
+		return nil
+	}
+	if err := ctrl.syncPodGroup(ctx, workload, minMember); err != nil {
+		return err
+	}
+	return ctrl.labelPods(ctx, workload)
+}
+
+// syncPodGroup creates the PodGroup of a workload, named after the workload, or updates its minMember.
+func (ctrl *WorkloadController) syncPodGroup(ctx context.Context, workload *unstructured.Unstructured, minMember int32) error {
+	pg, err := ctrl.pgLister.PodGroups(workload.GetNamespace()).Get(workload.GetName())
+	if apierrs.IsNotFound(err) {
+		pg = &schedv1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            workload.GetName(),
+				Namespace:       workload.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(workload, workload.GroupVersionKind())},
+			},
+			Spec: schedv1alpha1.PodGroupSpec{MinMember: minMember},
+		}
+		if _, err := ctrl.pgClient.SchedulingV1alpha1().PodGroups(pg.Namespace).Create(ctx, pg, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		klog.V(3).Infof("Created PodGroup %v/%v for %v", pg.Namespace, pg.Name, workload.GetKind())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner := metav1.GetControllerOf(pg); owner == nil || owner.UID != workload.GetUID() {
+		ctrl.eventRecorder.Eventf(workload, v1.EventTypeWarning, "PodGroupConflict",
+			"PodGroup %v is not owned by the %v", pg.Name, workload.GetKind())
+		return nil
+	}
+	if pg.Spec.MinMember == minMember {
+		return nil
+	}
+	pgCopy := pg.DeepCopy()
+	pgCopy.Spec.MinMember = minMember
+	patch, err := util.CreateMergePatch(pg, pgCopy)
+	if err != nil {
+		return err
+	}
+	_, err = ctrl.pgClient.SchedulingV1alpha1().PodGroups(pg.Namespace).Patch(ctx, pg.Name, types.MergePatchType,
+		patch, metav1.PatchOptions{})
+	return err
+}
+
+// labelPods sets the PodGroup of a workload on the pods it controls that were created before the PodGroup,
+// and thus were not labeled by the pod mutating webhook. Pods that already declare a PodGroup are left unchanged.
+func (ctrl *WorkloadController) labelPods(ctx context.Context, workload *unstructured.Unstructured) error {
+	pods, err := ctrl.podLister.Pods(workload.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != workload.GetUID() ||
+			len(ctrl.resolver.GetPodGroupName(pod)) != 0 {
+			continue
+		}
+		podCopy := pod.DeepCopy()
+		key := ctrl.resolver.PrimaryKey()
+		if key.Type == util.PodGroupKeyAnnotation {
+			metav1.SetMetaDataAnnotation(&podCopy.ObjectMeta, key.Key, workload.GetName())
+		} else {
+			metav1.SetMetaDataLabel(&podCopy.ObjectMeta, key.Key, workload.GetName())
+		}
+		patch, err := util.CreateMergePatch(pod, podCopy)
+		if err != nil {
+			return err
+		}
+		if _, err := ctrl.kubeClient.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch,
+			metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getWorkloadMinMember returns the minimum number of members of the PodGroup of a workload, from its annotation.
+func getWorkloadMinMember(workload *unstructured.Unstructured) (int32, error) {
+	value := workload.GetAnnotations()[util.PodGroupMinAvailableLabel]
+	minMember, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || minMember < 1 {
+		return 0, fmt.Errorf("invalid %v %q", util.PodGroupMinAvailableLabel, value)
+	}
+	return int32(minMember), nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+func TestWorkloadController(t *testing.T) {
+	ctx := context.TODO()
+	podTemplate := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "c", "image": "nginx"}},
+		},
+	}
+	statefulSet := makeWorkload("apps/v1", "StatefulSet", "sts", "3", map[string]interface{}{
+		"template": runtime.DeepCopyJSONValue(podTemplate),
+	})
+	mpiJob := makeWorkload("kubeflow.org/v1", "MPIJob", "mpi", "5", map[string]interface{}{
+		"mpiReplicaSpecs": map[string]interface{}{
+			"Launcher": map[string]interface{}{"replicas": int64(1), "template": runtime.DeepCopyJSONValue(podTemplate)},
+			"Worker":   map[string]interface{}{"replicas": int64(4), "template": runtime.DeepCopyJSONValue(podTemplate)},
+		},
+	})
+	job := makeWorkload("batch/v1", "Job", "job", "2", map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"controller-uid": "job"}},
+		"template": runtime.DeepCopyJSONValue(podTemplate),
+	})
+	jobPod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "default",
+		Labels:          map[string]string{"controller-uid": "job"},
+		OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(job, job.GroupVersionKind())}}}
+	otherPG := makePG("other", 1, "", nil)
+	conflicting := makeWorkload("apps/v1", "ReplicaSet", "other", "2", map[string]interface{}{
+		"template": runtime.DeepCopyJSONValue(podTemplate),
+	})
+
+	labeledJobPod := jobPod.DeepCopy()
+	labeledJobPod.Name = "job-2"
+	labeledJobPod.Labels[util.PodGroupLabel] = "other"
+
+	cases := []struct {
+		name      string
+		workload  *unstructured.Unstructured
+		minMember int32
+	}{
+		{name: "statefulset", workload: statefulSet, minMember: 3},
+		{name: "mpijob", workload: mpiJob, minMember: 5},
+		{name: "job", workload: job, minMember: 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl, kubeClient, pgClient := newTestWorkloadController(jobPod, labeledJobPod)
+			if err := ctrl.syncHandler(ctx, c.workload); err != nil {
+				t.Fatal(err)
+			}
+
+			pg, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, c.workload.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pg.Spec.MinMember != c.minMember {
+				t.Errorf("expected minMember %v, got %v", c.minMember, pg.Spec.MinMember)
+			}
+			if owner := metav1.GetControllerOf(pg); owner == nil || owner.UID != c.workload.GetUID() {
+				t.Errorf("expected the PodGroup to be controlled by %v, got %v", c.workload.GetName(), owner)
+			}
+
+			pod, err := kubeClient.CoreV1().Pods("default").Get(ctx, jobPod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, expected := pod.Labels[util.PodGroupLabel] == job.GetName(), c.workload == job; got != expected {
+				t.Errorf("expected the pod of the job to be labeled: %v, got %v", expected, got)
+			}
+			pod, err = kubeClient.CoreV1().Pods("default").Get(ctx, labeledJobPod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pod.Labels[util.PodGroupLabel] != "other" {
+				t.Errorf("expected the pod of another PodGroup to be left unchanged, got %v", pod.Labels)
+			}
+		})
+	}
+
+	t.Run("podgroup of another owner", func(t *testing.T) {
+		ctrl, _, pgClient := newTestWorkloadController(otherPG)
+		if err := ctrl.syncHandler(ctx, conflicting); err != nil {
+			t.Fatal(err)
+		}
+		pg, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "other", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pg.Spec.MinMember != otherPG.Spec.MinMember || len(pg.OwnerReferences) != 0 {
+			t.Errorf("expected the PodGroup of another owner to be left unchanged, got %+v", pg)
+		}
+	})
+
+	t.Run("minMember updated", func(t *testing.T) {
+		ownedPG := makePG("sts", 1, "", nil)
+		ownedPG.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(statefulSet, statefulSet.GroupVersionKind())}
+		ctrl, _, pgClient := newTestWorkloadController(ownedPG)
+		if err := ctrl.syncHandler(ctx, statefulSet); err != nil {
+			t.Fatal(err)
+		}
+		pg, err := pgClient.SchedulingV1alpha1().PodGroups("default").Get(ctx, "sts", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pg.Spec.MinMember != 3 {
+			t.Errorf("expected minMember 3, got %v", pg.Spec.MinMember)
+		}
+	})
+}
+
+// newTestWorkloadController returns a WorkloadController with fake clients and listers holding the objects,
+// which are pods or PodGroups.
+func newTestWorkloadController(objs ...runtime.Object) (*WorkloadController, *fake.Clientset, *pgfake.Clientset) {
+	kubeClient := fake.NewSimpleClientset()
+	pgClient := pgfake.NewSimpleClientset()
+	pgInformerFactory := schedinformer.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	podInformer := informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+	for _, obj := range objs {
+		switch obj := obj.(type) {
+		case *v1.Pod:
+			kubeClient.Tracker().Add(obj)
+			podInformer.Informer().GetStore().Add(obj)
+		case *v1alpha1.PodGroup:
+			pgClient.Tracker().Add(obj)
+			pgInformer.Informer().GetStore().Add(obj)
+		}
+	}
+	ctrl := &WorkloadController{
+		eventRecorder: record.NewFakeRecorder(10),
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Workload-queue"),
+		pgLister:      pgInformer.Lister(),
+		podLister:     podInformer.Lister(),
+		kubeClient:    kubeClient,
+		pgClient:      pgClient,
+		resolver:      util.DefaultPodGroupResolver,
+	}
+	return ctrl, kubeClient, pgClient
+}
+
+func makeWorkload(apiVersion, kind, name, minAvailable string, spec map[string]interface{}) *unstructured.Unstructured {
+	workload := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	workload.SetAPIVersion(apiVersion)
+	workload.SetKind(kind)
+	workload.SetName(name)
+	workload.SetNamespace("default")
+	workload.SetUID(types.UID(name))
+	workload.SetAnnotations(map[string]string{util.PodGroupMinAvailableLabel: minAvailable})
+	return workload
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/config"
+)
+
+// backfillStateKey is the key in CycleState to the resources held by waiting members, computed for backfill pods.
+const backfillStateKey = "PreFilter" + Name + "Backfill"
+
+// backfillState computed at PreFilter and used at Filter and Reserve.
+type backfillState struct {
+	// heldPods are the members waiting in permit on each node, whose PodGroups are not permitted yet.
+	heldPods map[string][]*v1.Pod
+	// heldGroups are the full names of the PodGroups of heldPods on each node.
+	heldGroups map[string]sets.String
+}
+
+// Clone the backfill state.
+func (s *backfillState) Clone() framework.StateData {
+	return s
+}
+
+// backfillIndex stores the backfill pods that use the resources held by the waiting members of PodGroups,
+// keyed by the full names of the PodGroups, so that they are evicted once the PodGroups are permitted.
+type backfillIndex struct {
+	sync.Mutex
+	pods map[string]map[types.UID]*v1.Pod
+}
+
+func newBackfillIndex() *backfillIndex {
+	return &backfillIndex{pods: make(map[string]map[types.UID]*v1.Pod)}
+}
+
+// add records that the backfill pod uses the resources held by the PodGroups.
+func (idx *backfillIndex) add(pod *v1.Pod, pgFullNames sets.String) {
+	idx.Lock()
+	defer idx.Unlock()
+	for _, pgFullName := range pgFullNames.List() {
+		pods, ok := idx.pods[pgFullName]
+		if !ok {
+			pods = make(map[types.UID]*v1.Pod)
+			idx.pods[pgFullName] = pods
+		}
+		pods[pod.UID] = pod
+	}
+}
+
+// take returns the backfill pods that use the resources held by the PodGroup, and forgets them.
+func (idx *backfillIndex) take(pgFullName string) []*v1.Pod {
+	idx.Lock()
+	defer idx.Unlock()
+	var result []*v1.Pod
+	for uid, pod := range idx.pods[pgFullName] {
+		result = append(result, pod)
+		idx.forgetLocked(uid)
+	}
+	delete(idx.pods, pgFullName)
+	sort.Slice(result, func(i, j int) bool { return result[i].UID < result[j].UID })
+	return result
+}
+
+// forgetPodGroup forgets the backfill pods that use the resources held by the PodGroup, e.g. when it is rejected.
+func (idx *backfillIndex) forgetPodGroup(pgFullName string) {
+	idx.Lock()
+	defer idx.Unlock()
+	delete(idx.pods, pgFullName)
+}
+
+// forgetPod forgets a backfill pod, e.g. when it fails to be bound.
+func (idx *backfillIndex) forgetPod(pod *v1.Pod) {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.forgetLocked(pod.UID)
+}
+
+func (idx *backfillIndex) forgetLocked(uid types.UID) {
+	for pgFullName, pods := range idx.pods {
+		delete(pods, uid)
+		if len(pods) == 0 {
+			delete(idx.pods, pgFullName)
+		}
+	}
+}
+
+// computeBackfillState collects the members waiting in permit whose PodGroups are not permitted yet.
+// Their resources are held until the PodGroups are permitted, and can be used by backfill pods meanwhile.
+func (cs *Coscheduling) computeBackfillState() *backfillState {
+	s := &backfillState{heldPods: make(map[string][]*v1.Pod), heldGroups: make(map[string]sets.String)}
+	cs.frameworkHandler.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		pod := waitingPod.GetPod()
+		nodeName := pod.Spec.NodeName
+		if len(nodeName) == 0 {
+			return
+		}
+		pgFullName, pg := cs.pgMgr.GetPodGroup(pod)
+		if pg == nil || cs.pgMgr.IsMinMemberAssigned(pg) {
+			return
+		}
+		s.heldPods[nodeName] = append(s.heldPods[nodeName], pod)
+		if _, ok := s.heldGroups[nodeName]; !ok {
+			s.heldGroups[nodeName] = sets.NewString()
+		}
+		s.heldGroups[nodeName].Insert(pgFullName)
+	})
+	return s
+}
+
+// newFit creates the NodeResourcesFit plugin whose filter is run by Coscheduling when backfill is enabled.
+func newFit(args *config.CoschedulingArgs, handle framework.FrameworkHandle) (*noderesources.Fit, error) {
+	fitArgs := &schedulerconfig.NodeResourcesFitArgs{}
+	if args.NodeResourcesFitArgs != nil {
+		fitArgs.IgnoredResources = args.NodeResourcesFitArgs.IgnoredResources
+		fitArgs.IgnoredResourceGroups = args.NodeResourcesFitArgs.IgnoredResourceGroups
+	}
+	p, err := noderesources.NewFit(fitArgs, handle)
+	if err != nil {
+		return nil, err
+	}
+	return p.(*noderesources.Fit), nil
+}
+
+// filterResources runs the NodeResourcesFit filter. Backfill pods that do not fit the node are filtered again
+// on a copy of the node without the members waiting in permit, so that they may use the resources held by them.
+func (cs *Coscheduling) filterResources(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	status := cs.fit.Filter(ctx, state, pod, nodeInfo)
+	if status.IsSuccess() || !cs.isBackfillPod(pod) {
+		return status
+	}
+	s, err := getBackfillState(state)
+	if err != nil {
+		return status
+	}
+	held := s.heldPods[nodeInfo.Node().Name]
+	if len(held) == 0 {
+		return status
+	}
+	released := nodeInfo.Clone()
+	for _, p := range held {
+		if err := released.RemovePod(p); err != nil {
+			klog.Errorf("Cannot release the resources held by pod %v/%v: %v", p.Namespace, p.Name, err)
+			return status
+		}
+	}
+	if cs.fit.Filter(ctx, state, pod, released).IsSuccess() {
+		return nil
+	}
+	return status
+}
+
+// reserveBackfillPod records a backfill pod that uses the resources held by members waiting in permit on the node.
+func (cs *Coscheduling) reserveBackfillPod(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	s, err := getBackfillState(state)
+	if err != nil || s.heldGroups[nodeName].Len() == 0 || cs.backfills == nil {
+		return
+	}
+	nodeInfo, err := cs.frameworkHandler.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		klog.Errorf("Cannot get node %v from the snapshot: %v", nodeName, err)
+		return
+	}
+	if !cs.fit.Filter(ctx, state, pod, nodeInfo).IsSuccess() {
+		klog.V(4).Infof("Backfill pod %v/%v uses the resources held by PodGroups %v on node %v", pod.Namespace, pod.Name,
+			s.heldGroups[nodeName].List(), nodeName)
+		cs.backfills.add(pod, s.heldGroups[nodeName])
+	}
+}
+
+// evictBackfillPods evicts the backfill pods that use the resources held by the members of a PodGroup,
+// before the members are permitted to be bound. The pods are deleted immediately, so that the kubelets
+// admit the members.
+func (cs *Coscheduling) evictBackfillPods(ctx context.Context, pgFullName string) {
+	if cs.backfills == nil {
+		return
+	}
+	zero := int64(0)
+	for _, pod := range cs.backfills.take(pgFullName) {
+		err := cs.frameworkHandler.ClientSet().CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name,
+			metav1.DeleteOptions{GracePeriodSeconds: &zero, Preconditions: metav1.NewUIDPreconditions(string(pod.UID))})
+		if err != nil {
+			if !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+				klog.Errorf("Error evicting backfill pod %v/%v: %v", pod.Namespace, pod.Name, err)
+			}
+			continue
+		}
+		klog.V(3).Infof("Evicted backfill pod %v/%v for PodGroup %v", pod.Namespace, pod.Name, pgFullName)
+		cs.frameworkHandler.EventRecorder().Eventf(pod, nil, v1.EventTypeNormal, "Preempted", "Preempting",
+			"Evicted to release the resources held by PodGroup %v", pgFullName)
+	}
+}
+
+func getBackfillState(cycleState *framework.CycleState) (*backfillState, error) {
+	c, err := cycleState.Read(backfillStateKey)
+	if err != nil {
+		// backfillState doesn't exist, likely the pod is not a backfill pod.
+		return nil, fmt.Errorf("error reading %q from cycleState: %v", backfillStateKey, err)
+	}
+
+	s, ok := c.(*backfillState)
+	if !ok {
+		return nil, fmt.Errorf("%+v  convert to Coscheduling.backfillState error", c)
+	}
+	return s, nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// handleBindFailure compensates for a member of a permitted PodGroup that failed to be bound, according to
+// spec.bindFailurePolicy of the PodGroup:
+// 1. the member is sorted ahead of the other pods of the same priority once the scheduler re-queues it,
+// without backing off the PodGroup.
+// 2. with the Rollback policy, the members that have been assigned are evicted as well. Members waiting in
+// permit are rejected, and members that are being bound are evicted once they are bound.
+func (cs *Coscheduling) handleBindFailure(pod *v1.Pod, pg *v1alpha1.PodGroup) {
+	cs.pgMgr.RecordBindFailure(pod, pg)
+	if pg.Spec.BindFailurePolicy != v1alpha1.BindFailureRollback {
+		return
+	}
+	for _, member := range cs.pgMgr.RollbackMembers(pg, pod) {
+		if waitingPod := cs.frameworkHandler.GetWaitingPod(member.UID); waitingPod != nil {
+			waitingPod.Reject(cs.Name())
+			continue
+		}
+		cs.rollbackMember(member, pod.Name, pg)
+	}
+}
+
+// rollbackMember evicts a bound member of a PodGroup, as another member failed to be bound.
+func (cs *Coscheduling) rollbackMember(member *v1.Pod, failed string, pg *v1alpha1.PodGroup) {
+	if err := util.DeletePod(cs.frameworkHandler.ClientSet(), member); err != nil {
+		klog.Errorf("Error evicting pod %v/%v: %v", member.Namespace, member.Name, err)
+		return
+	}
+	cs.frameworkHandler.EventRecorder().Eventf(member, nil, v1.EventTypeNormal, "RolledBack", "Evicting",
+		"Evicted as member %v of PodGroup %v/%v failed to be bound", failed, pg.Namespace, pg.Name)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// assignedPodIndex indexes the pods that have been assigned a node, assumed or bound, by their podGroups.
+type assignedPodIndex struct {
+	sync.RWMutex
+	// pods maps the full name of a podGroup to its assigned pods, keyed by the namespaced names of the pods.
+	pods map[string]map[string]*corev1.Pod
+	// podGroups maps the namespaced name of an assigned pod to the full name of its podGroup.
+	podGroups map[string]string
+}
+
+func newAssignedPodIndex() *assignedPodIndex {
+	return &assignedPodIndex{
+		pods:      make(map[string]map[string]*corev1.Pod),
+		podGroups: make(map[string]string),
+	}
+}
+
+// add adds or updates an assigned pod of the podGroup.
+func (i *assignedPodIndex) add(pgFullName string, pod *corev1.Pod) {
+	key := GetNamespacedName(pod)
+	i.Lock()
+	defer i.Unlock()
+	if old, ok := i.podGroups[key]; ok && old != pgFullName {
+		i.removeLocked(key)
+	}
+	pods, ok := i.pods[pgFullName]
+	if !ok {
+		pods = make(map[string]*corev1.Pod)
+		i.pods[pgFullName] = pods
+	}
+	pods[key] = pod
+	i.podGroups[key] = pgFullName
+}
+
+// remove removes a pod from the index.
+func (i *assignedPodIndex) remove(pod *corev1.Pod) {
+	i.Lock()
+	defer i.Unlock()
+	i.removeLocked(GetNamespacedName(pod))
+}
+
+func (i *assignedPodIndex) removeLocked(key string) {
+	pgFullName, ok := i.podGroups[key]
+	if !ok {
+		return
+	}
+	delete(i.podGroups, key)
+	delete(i.pods[pgFullName], key)
+	if len(i.pods[pgFullName]) == 0 {
+		delete(i.pods, pgFullName)
+	}
+}
+
+// list returns the assigned pods of the podGroup.
+func (i *assignedPodIndex) list(pgFullName string) []*corev1.Pod {
+	i.RLock()
+	defer i.RUnlock()
+	pods := make([]*corev1.Pod, 0, len(i.pods[pgFullName]))
+	for _, pod := range i.pods[pgFullName] {
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+// count returns the number of assigned pods of the podGroup.
+func (i *assignedPodIndex) count(pgFullName string) int {
+	i.RLock()
+	defer i.RUnlock()
+	return len(i.pods[pgFullName])
+}
+
+// AssumePod records a pod that is reserved on a node as assigned, before it is bound.
+func (pgMgr *PodGroupManager) AssumePod(pod *corev1.Pod, nodeName string) {
+	pgFullName := pgMgr.resolver.GetPodGroupFullName(pod)
+	if len(pgFullName) == 0 {
+		return
+	}
+	podCopy := pod.DeepCopy()
+	podCopy.Spec.NodeName = nodeName
+	pgMgr.assignedPods.add(pgFullName, podCopy)
+}
+
+// ForgetPod forgets a pod that was assumed but is unreserved, e.g. rejected in permit or failed to be bound.
+func (pgMgr *PodGroupManager) ForgetPod(pod *corev1.Pod) {
+	pgMgr.assignedPods.remove(pod)
+	pgMgr.forgetPermitted(pod)
+}
+
+// onPodAdd indexes a pod that is bound to a node.
+func (pgMgr *PodGroupManager) onPodAdd(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || len(pod.Spec.NodeName) == 0 {
+		return
+	}
+	pgMgr.forgetBindFailure(pod)
+	pgFullName := pgMgr.resolver.GetPodGroupFullName(pod)
+	if len(pgFullName) == 0 {
+		pgMgr.assignedPods.remove(pod)
+		return
+	}
+	pgMgr.assignedPods.add(pgFullName, pod)
+}
+
+// onPodUpdate indexes a pod that is bound to a node. Updates of pods without a node are ignored,
+// so that pods which are assumed but not bound yet remain indexed.
+func (pgMgr *PodGroupManager) onPodUpdate(oldObj, newObj interface{}) {
+	pgMgr.onPodAdd(newObj)
+}
+
+// onPodDelete removes a deleted pod from the index, forgets its bind failure and permit, and forgets its
+// lightweight PodGroup once none of its pods are left.
+func (pgMgr *PodGroupManager) onPodDelete(obj interface{}) {
+	var pod *corev1.Pod
+	switch t := obj.(type) {
+	case *corev1.Pod:
+		pod = t
+	case cache.DeletedFinalStateUnknown:
+		var ok bool
+		if pod, ok = t.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	default:
+		return
+	}
+	pgMgr.assignedPods.remove(pod)
+	pgMgr.forgetBindFailure(pod)
+	pgMgr.forgetPermitted(pod)
+	pgMgr.forgetLightweightPodGroup(pod)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestAssignedPodIndex(t *testing.T) {
+	pgMgr := &PodGroupManager{resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex()}
+	expectCount := func(pgName string, expected int) {
+		t.Helper()
+		if got := pgMgr.calculateAssignedPods(pgName, "ns1"); got != expected {
+			t.Errorf("expected %v assigned pods of %v, got %v", expected, pgName, got)
+		}
+	}
+
+	bound := st.MakePod().Name("p1").UID("p1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Node("node1").Obj()
+	pending := st.MakePod().Name("p2").UID("p2").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj()
+	pgMgr.onPodAdd(bound)
+	pgMgr.onPodAdd(pending)
+	expectCount("pg1", 1)
+
+	// An assumed pod is counted until it is bound, even if it is updated in the meantime.
+	pgMgr.AssumePod(pending, "node2")
+	expectCount("pg1", 2)
+	pgMgr.onPodUpdate(pending, pending)
+	expectCount("pg1", 2)
+	if pods := pgMgr.getAssignedPods("pg1", "ns1"); !containsPod(pods, pending) {
+		t.Errorf("expected the assumed pod to be listed, got %v", pods)
+	}
+
+	pgMgr.ForgetPod(pending)
+	expectCount("pg1", 1)
+
+	// A pod moved to another podGroup is only counted for the new one.
+	moved := bound.DeepCopy()
+	moved.Labels[util.PodGroupLabel] = "pg2"
+	pgMgr.onPodUpdate(bound, moved)
+	expectCount("pg1", 0)
+	expectCount("pg2", 1)
+
+	pgMgr.onPodDelete(cache.DeletedFinalStateUnknown{Key: "ns1/p1", Obj: moved})
+	expectCount("pg2", 0)
+}
+
+// getAssignedPodsFromSnapshot lists the assigned pods of a podGroup by walking every pod of the snapshot.
+func getAssignedPodsFromSnapshot(snapshot framework.SharedLister, podGroupName, namespace string) []*corev1.Pod {
+	nodeInfos, _ := snapshot.NodeInfos().List()
+	var pods []*corev1.Pod
+	for _, nodeInfo := range nodeInfos {
+		for _, podInfo := range nodeInfo.Pods {
+			pod := podInfo.Pod
+			if util.DefaultPodGroupResolver.IsMember(pod, namespace, podGroupName) && pod.Spec.NodeName != "" {
+				pods = append(pods, pod)
+			}
+		}
+	}
+	return pods
+}
+
+func BenchmarkGetAssignedPods(b *testing.B) {
+	for _, nodesNum := range []int{100, 1000, 5000} {
+		existingPods, allNodes := testutil.MakeNodesAndPods(map[string]string{"test": "a"}, nodesNum*10, nodesNum)
+		members := existingPods[:10]
+		for _, pod := range members {
+			pod.Labels[util.PodGroupLabel] = "pg1"
+		}
+		snapshot := testutil.NewFakeSharedLister(existingPods, allNodes)
+		pgMgr := &PodGroupManager{resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex()}
+		for _, pod := range existingPods {
+			pgMgr.onPodAdd(pod)
+		}
+
+		b.Run(fmt.Sprintf("snapshot/%d nodes", nodesNum), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				getAssignedPodsFromSnapshot(snapshot, "pg1", "")
+			}
+		})
+		b.Run(fmt.Sprintf("index/%d nodes", nodesNum), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pgMgr.getAssignedPods("pg1", "")
+			}
+		})
+	}
+}
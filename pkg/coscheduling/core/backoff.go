@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// backoffEntry is the backoff state of a PodGroup.
+type backoffEntry struct {
+	// backoff is the duration of the last backoff.
+	backoff time.Duration
+	// lastUpdate is the time of the last backoff.
+	lastUpdate time.Time
+}
+
+// PodGroupBackoff tracks the backoff of PodGroups that failed to be scheduled.
+// The backoff of a PodGroup starts from initialBackoff and doubles on every failure up to maxBackoff.
+type PodGroupBackoff struct {
+	sync.Mutex
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	clock          clock.Clock
+	entries        map[string]*backoffEntry
+}
+
+// NewPodGroupBackoff creates a new PodGroupBackoff.
+func NewPodGroupBackoff(initialBackoff, maxBackoff time.Duration, c clock.Clock) *PodGroupBackoff {
+	return &PodGroupBackoff{
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		clock:          c,
+		entries:        make(map[string]*backoffEntry),
+	}
+}
+
+// Backoff starts the next backoff of the PodGroup, unless it is already backing off,
+// and returns the duration of the current backoff.
+func (b *PodGroupBackoff) Backoff(pgFullName string) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	now := b.clock.Now()
+	b.gc(now)
+	entry, ok := b.entries[pgFullName]
+	if !ok {
+		entry = &backoffEntry{backoff: b.initialBackoff}
+		b.entries[pgFullName] = entry
+	} else if now.Before(entry.lastUpdate.Add(entry.backoff)) {
+		// Failures of other members during the current backoff, e.g. when all waiting
+		// members are rejected at once, are counted as the same failure.
+		return entry.backoff
+	} else {
+		entry.backoff *= 2
+		if entry.backoff > b.maxBackoff {
+			entry.backoff = b.maxBackoff
+		}
+	}
+	entry.lastUpdate = now
+	return entry.backoff
+}
+
+// Remaining returns the remaining time of the current backoff of the PodGroup,
+// zero if the PodGroup is not backing off.
+func (b *PodGroupBackoff) Remaining(pgFullName string) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	entry, ok := b.entries[pgFullName]
+	if !ok {
+		return 0
+	}
+	remaining := entry.lastUpdate.Add(entry.backoff).Sub(b.clock.Now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Reset forgets the backoff of the PodGroup, so that the next backoff starts from initialBackoff.
+// It returns false if the PodGroup was not tracked.
+func (b *PodGroupBackoff) Reset(pgFullName string) bool {
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.entries[pgFullName]; !ok {
+		return false
+	}
+	delete(b.entries, pgFullName)
+	return true
+}
+
+// gc forgets PodGroups that have not failed for twice of maxBackoff after their last backoff ended,
+// e.g. deleted PodGroups.
+func (b *PodGroupBackoff) gc(now time.Time) {
+	for pgFullName, entry := range b.entries {
+		if now.Sub(entry.lastUpdate.Add(entry.backoff)) > 2*b.maxBackoff {
+			delete(b.entries, pgFullName)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestPodGroupBackoff(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	b := NewPodGroupBackoff(time.Second, 4*time.Second, fakeClock)
+
+	if remaining := b.Remaining("ns1/pg1"); remaining != 0 {
+		t.Errorf("expected no backoff, got %v", remaining)
+	}
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if got := b.Backoff("ns1/pg1"); got != expected {
+			t.Errorf("backoff %d: expected %v, got %v", i, expected, got)
+		}
+		// Failures during the current backoff do not increase it.
+		if got := b.Backoff("ns1/pg1"); got != expected {
+			t.Errorf("backoff %d during backoff: expected %v, got %v", i, expected, got)
+		}
+		if remaining := b.Remaining("ns1/pg1"); remaining != expected {
+			t.Errorf("backoff %d: expected remaining %v, got %v", i, expected, remaining)
+		}
+		fakeClock.Step(expected)
+		if remaining := b.Remaining("ns1/pg1"); remaining != 0 {
+			t.Errorf("backoff %d: expected backoff to end, got remaining %v", i, remaining)
+		}
+	}
+
+	if !b.Reset("ns1/pg1") {
+		t.Error("expected the backoff to be reset")
+	}
+	if b.Reset("ns1/pg1") {
+		t.Error("expected no backoff to reset")
+	}
+	if got := b.Backoff("ns1/pg1"); got != time.Second {
+		t.Errorf("expected backoff to restart from %v, got %v", time.Second, got)
+	}
+
+	// PodGroups that have not failed for a long time are forgotten.
+	fakeClock.Step(10 * time.Second)
+	b.Backoff("ns1/pg2")
+	if _, ok := b.entries["ns1/pg1"]; ok {
+		t.Error("expected ns1/pg1 to be garbage collected")
+	}
+}
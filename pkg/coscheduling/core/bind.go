@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// RecordBindFailure records that a member of a permitted podGroup failed to be bound, in the status of the
+// podGroup. The pod is reported by IsBindFailed until it is bound or deleted.
+func (pgMgr *PodGroupManager) RecordBindFailure(pod *corev1.Pod, pg *v1alpha1.PodGroup) {
+	pgMgr.bindFailedLock.Lock()
+	pgMgr.bindFailedPods.Insert(GetNamespacedName(pod))
+	pgMgr.bindFailedLock.Unlock()
+	pgMgr.setUnschedulable(pg, v1alpha1.PodGroupBindFailedReason,
+		fmt.Sprintf("member %v failed to be bound after the pod group was permitted", pod.Name))
+}
+
+// IsBindFailed returns whether the pod is a member of a permitted podGroup that failed to be bound.
+func (pgMgr *PodGroupManager) IsBindFailed(pod *corev1.Pod) bool {
+	pgMgr.bindFailedLock.RLock()
+	defer pgMgr.bindFailedLock.RUnlock()
+	return pgMgr.bindFailedPods.Has(GetNamespacedName(pod))
+}
+
+// forgetBindFailure forgets the bind failure of a pod that is bound or deleted.
+func (pgMgr *PodGroupManager) forgetBindFailure(pod *corev1.Pod) {
+	pgMgr.bindFailedLock.Lock()
+	defer pgMgr.bindFailedLock.Unlock()
+	pgMgr.bindFailedPods.Delete(GetNamespacedName(pod))
+}
+
+// MarkPermitted records that a member is permitted, so that it is known to have failed to be bound if it is
+// unreserved before PostBind.
+func (pgMgr *PodGroupManager) MarkPermitted(pod *corev1.Pod) {
+	pgMgr.bindFailedLock.Lock()
+	defer pgMgr.bindFailedLock.Unlock()
+	pgMgr.permittedPods.Insert(GetNamespacedName(pod))
+}
+
+// IsPermitted returns whether the pod is a member that is permitted but not bound yet.
+func (pgMgr *PodGroupManager) IsPermitted(pod *corev1.Pod) bool {
+	pgMgr.bindFailedLock.RLock()
+	defer pgMgr.bindFailedLock.RUnlock()
+	return pgMgr.permittedPods.Has(GetNamespacedName(pod))
+}
+
+// RollbackMembers returns the assigned members of a podGroup other than the failed one that are not being bound,
+// to be evicted now. The members that are permitted but not bound yet are recorded instead, and reported by
+// TakeRollback once they are bound, as they cannot be evicted before.
+func (pgMgr *PodGroupManager) RollbackMembers(pg *v1alpha1.PodGroup, failed *corev1.Pod) []*corev1.Pod {
+	pgMgr.bindFailedLock.Lock()
+	defer pgMgr.bindFailedLock.Unlock()
+	var members []*corev1.Pod
+	for _, member := range pgMgr.GetAssignedMembers(pg) {
+		if member.UID == failed.UID {
+			continue
+		}
+		key := GetNamespacedName(member)
+		if pgMgr.permittedPods.Has(key) {
+			pgMgr.rollbackPods[key] = failed.Name
+			continue
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// TakeRollback is called once a member is bound. It returns the name of the member that failed to be bound,
+// if the member is to be evicted to roll back its podGroup.
+func (pgMgr *PodGroupManager) TakeRollback(pod *corev1.Pod) (string, bool) {
+	key := GetNamespacedName(pod)
+	pgMgr.bindFailedLock.Lock()
+	defer pgMgr.bindFailedLock.Unlock()
+	pgMgr.permittedPods.Delete(key)
+	failed, ok := pgMgr.rollbackPods[key]
+	delete(pgMgr.rollbackPods, key)
+	return failed, ok
+}
+
+// forgetPermitted forgets a member that is unreserved or deleted.
+func (pgMgr *PodGroupManager) forgetPermitted(pod *corev1.Pod) {
+	key := GetNamespacedName(pod)
+	pgMgr.bindFailedLock.Lock()
+	defer pgMgr.bindFailedLock.Unlock()
+	pgMgr.permittedPods.Delete(key)
+	delete(pgMgr.rollbackPods, key)
+}
+
+// GetAssignedMembers returns the pods of a podGroup that have been assigned a node: assumed or bound.
+func (pgMgr *PodGroupManager) GetAssignedMembers(pg *v1alpha1.PodGroup) []*corev1.Pod {
+	return pgMgr.getAssignedPods(pg.Name, pg.Namespace)
+}
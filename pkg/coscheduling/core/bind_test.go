@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestBindFailure(t *testing.T) {
+	pg := testutil.MakePG("pg", "ns1", 2, nil, nil)
+	pgClient := fakepgclientset.NewSimpleClientset(pg)
+	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformer.Informer().GetStore().Add(pg)
+	pgMgr := &PodGroupManager{pgClient: pgClient, pgLister: pgInformer.Lister(), backoff: newBackoff(),
+		backoffStatuses: newBackoffStatusQueue(), resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex(), bindFailedPods: sets.NewString(),
+		permittedPods: sets.NewString(), rollbackPods: make(map[string]string)}
+
+	bound := st.MakePod().Name("p1").UID("p1").Namespace("ns1").Label(util.PodGroupLabel, "pg").Node("node1").Obj()
+	failed := st.MakePod().Name("p2").UID("p2").Namespace("ns1").Label(util.PodGroupLabel, "pg").Obj()
+	pgMgr.onPodAdd(bound)
+	pgMgr.AssumePod(failed, "node2")
+	if !pgMgr.IsMinMemberAssigned(pg) {
+		t.Fatal("expected minMember pods of the pod group to be assigned")
+	}
+	if members := pgMgr.GetAssignedMembers(pg); len(members) != 2 {
+		t.Errorf("expected 2 assigned members, got %v", members)
+	}
+
+	pgMgr.ForgetPod(failed)
+	pgMgr.RecordBindFailure(failed, pg)
+	if !pgMgr.IsBindFailed(failed) || pgMgr.IsBindFailed(bound) {
+		t.Errorf("expected only %v to be bind failed", failed.Name)
+	}
+	got, err := pgClient.SchedulingV1alpha1().PodGroups("ns1").Get(context.Background(), "pg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition := util.GetPodGroupCondition(&got.Status, v1alpha1.PodGroupScheduledCondition)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != v1alpha1.PodGroupBindFailedReason {
+		t.Errorf("expected the pod group to be unschedulable with reason %v, got %+v", v1alpha1.PodGroupBindFailedReason, condition)
+	}
+
+	// The failure is forgotten once the pod is bound.
+	boundAgain := failed.DeepCopy()
+	boundAgain.Spec.NodeName = "node2"
+	pgMgr.onPodUpdate(failed, boundAgain)
+	if pgMgr.IsBindFailed(failed) {
+		t.Errorf("expected the bind failure of %v to be forgotten", failed.Name)
+	}
+}
+
+func TestRollbackMembers(t *testing.T) {
+	pg := testutil.MakePG("pg", "ns1", 3, nil, nil)
+	pgMgr := &PodGroupManager{resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex(),
+		bindFailedPods: sets.NewString(), permittedPods: sets.NewString(), rollbackPods: make(map[string]string)}
+
+	bound := st.MakePod().Name("p1").UID("p1").Namespace("ns1").Label(util.PodGroupLabel, "pg").Node("node1").Obj()
+	binding := st.MakePod().Name("p2").UID("p2").Namespace("ns1").Label(util.PodGroupLabel, "pg").Obj()
+	failed := st.MakePod().Name("p3").UID("p3").Namespace("ns1").Label(util.PodGroupLabel, "pg").Obj()
+	pgMgr.onPodAdd(bound)
+	for _, pod := range []*corev1.Pod{binding, failed} {
+		pgMgr.AssumePod(pod, "node2")
+		pgMgr.MarkPermitted(pod)
+	}
+
+	// The failed member is permitted until it is unreserved.
+	if !pgMgr.IsPermitted(failed) {
+		t.Errorf("expected %v to be permitted", failed.Name)
+	}
+	pgMgr.ForgetPod(failed)
+	if pgMgr.IsPermitted(failed) {
+		t.Errorf("expected %v to be forgotten", failed.Name)
+	}
+
+	// Only the bound member is evicted now, and the member being bound once it is bound.
+	members := pgMgr.RollbackMembers(pg, failed)
+	if len(members) != 1 || members[0].Name != bound.Name {
+		t.Errorf("expected only %v to be evicted now, got %v", bound.Name, members)
+	}
+	if name, ok := pgMgr.TakeRollback(binding); !ok || name != failed.Name {
+		t.Errorf("expected %v to be rolled back for %v, got %q, %v", binding.Name, failed.Name, name, ok)
+	}
+	if pgMgr.IsPermitted(binding) {
+		t.Errorf("expected %v to be forgotten once bound", binding.Name)
+	}
+	if _, ok := pgMgr.TakeRollback(binding); ok {
+		t.Errorf("expected %v to be rolled back only once", binding.Name)
+	}
+}
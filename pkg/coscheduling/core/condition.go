@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// setUnschedulable records in the Scheduled condition of a podGroup the reason why it cannot be scheduled.
+func (pgMgr *PodGroupManager) setUnschedulable(pg *v1alpha1.PodGroup, reason, message string) {
+	pgCopy := pg.DeepCopy()
+	transitioned := util.SetPodGroupCondition(&pgCopy.Status, v1alpha1.PodGroupCondition{
+		Type:    v1alpha1.PodGroupScheduledCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	if pgMgr.patchPodGroupStatus(pg, pgCopy) && transitioned {
+		pgMgr.recordEvent(pg, corev1.EventTypeWarning, reason, message)
+	}
+}
+
+// IsMinMemberAssigned returns whether minMember pods of a podGroup have been assigned a node: assumed or bound.
+func (pgMgr *PodGroupManager) IsMinMemberAssigned(pg *v1alpha1.PodGroup) bool {
+	return pgMgr.calculateAssignedPods(pg.Name, pg.Namespace) >= int(pg.Spec.MinMember)
+}
+
+// patchBackoff patches status.backoffSeconds of a podGroup, together with its BackingOff condition.
+// If a reason is given, the Scheduled condition is set to false with the reason in the same patch.
+// An event is recorded whenever a new backoff starts.
+func (pgMgr *PodGroupManager) patchBackoff(pg *v1alpha1.PodGroup, backoffSeconds int32, reason, message string) {
+	pgCopy := pg.DeepCopy()
+	pgCopy.Status.BackoffSeconds = backoffSeconds
+	condition := v1alpha1.PodGroupCondition{
+		Type:    v1alpha1.PodGroupBackingOffCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1alpha1.PodGroupBackoffReason,
+		Message: fmt.Sprintf("pods are denied for %vs after the pod group failed to be scheduled", backoffSeconds),
+	}
+	if backoffSeconds == 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = v1alpha1.PodGroupBackoffResetReason
+		condition.Message = "the pod group is permitted"
+	}
+	backoffStarted := backoffSeconds != 0 && backoffSeconds != pg.Status.BackoffSeconds
+	backoffTransitioned := util.SetPodGroupCondition(&pgCopy.Status, condition)
+	unschedulableTransitioned := len(reason) != 0 && util.SetPodGroupCondition(&pgCopy.Status, v1alpha1.PodGroupCondition{
+		Type:    v1alpha1.PodGroupScheduledCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	if !pgMgr.patchPodGroupStatus(pg, pgCopy) {
+		return
+	}
+	if unschedulableTransitioned {
+		pgMgr.recordEvent(pg, corev1.EventTypeWarning, reason, message)
+	}
+	if backoffStarted {
+		pgMgr.recordEvent(pg, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	} else if backoffTransitioned && backoffSeconds == 0 {
+		pgMgr.recordEvent(pg, corev1.EventTypeNormal, condition.Reason, condition.Message)
+	}
+}
+
+// patchPodGroupStatus patches the status of a podGroup if it changes, and returns whether it is patched.
+func (pgMgr *PodGroupManager) patchPodGroupStatus(old, new *v1alpha1.PodGroup) bool {
+	if reflect.DeepEqual(old.Status, new.Status) {
+		return false
+	}
+	patch, err := util.CreateMergePatch(old, new)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+	if err := pgMgr.PatchPodGroup(old.Name, old.Namespace, patch); err != nil {
+		klog.Errorf("Failed to patch status of PodGroup %v/%v: %v", old.Namespace, old.Name, err)
+		return false
+	}
+	return true
+}
+
+// recordEvent records an event of a podGroup. Lightweight podGroups do not exist in the API server,
+// so no events are recorded for them.
+func (pgMgr *PodGroupManager) recordEvent(pg *v1alpha1.PodGroup, eventType, reason, message string) {
+	if pgMgr.eventRecorder == nil {
+		return
+	}
+	if _, err := pgMgr.pgLister.PodGroups(pg.Namespace).Get(pg.Name); err != nil {
+		return
+	}
+	pgMgr.eventRecorder.Eventf(pg, nil, eventType, reason, "Scheduling", "%v", message)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestPodGroupConditions(t *testing.T) {
+	ctx := context.Background()
+	pg := testutil.MakePG("pg", "ns1", 2, nil, nil)
+	pgClient := fakepgclientset.NewSimpleClientset(pg)
+	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformer.Informer().GetStore().Add(pg)
+	recorder := events.NewFakeRecorder(10)
+	pgMgr := &PodGroupManager{pgClient: pgClient, pgLister: pgInformer.Lister(), backoff: newBackoff(),
+		backoffStatuses: newBackoffStatusQueue(), resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex(), eventRecorder: recorder}
+
+	expectCondition := func(conditionType v1alpha1.PodGroupConditionType, status corev1.ConditionStatus, reason string) {
+		t.Helper()
+		got, err := pgClient.SchedulingV1alpha1().PodGroups("ns1").Get(ctx, "pg", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pgInformer.Informer().GetStore().Update(got)
+		condition := util.GetPodGroupCondition(&got.Status, conditionType)
+		if condition == nil || condition.Status != status || condition.Reason != reason {
+			t.Errorf("expected condition %v to be %v with reason %v, got %+v", conditionType, status, reason, condition)
+		}
+	}
+	expectEvent := func(reason string) {
+		t.Helper()
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, reason) {
+				t.Errorf("expected an event with reason %v, got %q", reason, event)
+			}
+		default:
+			t.Errorf("expected an event with reason %v", reason)
+		}
+	}
+
+	pgMgr.setUnschedulable(pg, v1alpha1.PodGroupNotEnoughMembersReason, "1 of 2 members have been created")
+	expectCondition(v1alpha1.PodGroupScheduledCondition, corev1.ConditionFalse, v1alpha1.PodGroupNotEnoughMembersReason)
+	expectEvent(v1alpha1.PodGroupNotEnoughMembersReason)
+
+	// A changed message of the same reason is not recorded as an event again.
+	_, pg = pgMgr.GetPodGroup(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1",
+		Labels: map[string]string{util.PodGroupLabel: "pg"}}})
+	pgMgr.setUnschedulable(pg, v1alpha1.PodGroupNotEnoughMembersReason, "0 of 2 members have been created")
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event, got %q", <-recorder.Events)
+	}
+
+	// Backoffs are recorded in the status asynchronously, and only the latest one of a pod group is patched.
+	pgMgr.BackoffPodGroup("ns1/pg", v1alpha1.PodGroupNotEnoughMembersReason, "1 of 2 members have been created")
+	pgMgr.BackoffPodGroup("ns1/pg", v1alpha1.PodGroupInsufficientResourcesReason, "resource gap")
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event before the backoff is processed, got %q", <-recorder.Events)
+	}
+	pgMgr.processNextBackoffStatus()
+	if pgMgr.backoffStatuses.queue.Len() != 0 {
+		t.Errorf("expected the backoffs of the pod group to be coalesced, got %v queued", pgMgr.backoffStatuses.queue.Len())
+	}
+	expectCondition(v1alpha1.PodGroupScheduledCondition, corev1.ConditionFalse, v1alpha1.PodGroupInsufficientResourcesReason)
+	expectCondition(v1alpha1.PodGroupBackingOffCondition, corev1.ConditionTrue, v1alpha1.PodGroupBackoffReason)
+	expectEvent(v1alpha1.PodGroupInsufficientResourcesReason)
+	expectEvent(v1alpha1.PodGroupBackoffReason)
+
+	_, pg = pgMgr.GetPodGroup(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1",
+		Labels: map[string]string{util.PodGroupLabel: "pg"}}})
+	pgMgr.resetBackoff("ns1/pg", pg)
+	pgMgr.processNextBackoffStatus()
+	expectCondition(v1alpha1.PodGroupBackingOffCondition, corev1.ConditionFalse, v1alpha1.PodGroupBackoffResetReason)
+	expectEvent(v1alpha1.PodGroupBackoffResetReason)
+}
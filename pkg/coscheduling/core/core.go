@@ -19,6 +19,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -28,8 +29,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
 	informerv1 "k8s.io/client-go/informers/core/v1"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/klog/v2"
 	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
 
@@ -46,8 +51,24 @@ type Manager interface {
 	Permit(context.Context, *corev1.Pod, string) (bool, error)
 	PostBind(context.Context, *corev1.Pod, string)
 	GetPodGroup(*corev1.Pod) (string, *v1alpha1.PodGroup)
+	GetPodGroupPods(*corev1.Pod) ([]*corev1.Pod, error)
 	GetCreationTimestamp(*corev1.Pod, time.Time) time.Time
-	AddDeniedPodGroup(string)
+	BackoffPodGroup(string, string, string)
+	GetPlacedDomain(*v1alpha1.PodGroup, string) string
+	GetTopologyDomains(*corev1.Pod, *v1alpha1.PodGroup, string) sets.String
+	CountPlacedMembers(*v1alpha1.PodGroup, string) map[string]int
+	ReserveNodes(*corev1.Pod, int32, time.Duration)
+	GetNodeReservation(string) (string, int32)
+	AssumePod(*corev1.Pod, string)
+	ForgetPod(*corev1.Pod)
+	IsMinMemberAssigned(*v1alpha1.PodGroup) bool
+	RecordBindFailure(*corev1.Pod, *v1alpha1.PodGroup)
+	IsBindFailed(*corev1.Pod) bool
+	GetAssignedMembers(*v1alpha1.PodGroup) []*corev1.Pod
+	MarkPermitted(*corev1.Pod)
+	IsPermitted(*corev1.Pod) bool
+	RollbackMembers(*v1alpha1.PodGroup, *corev1.Pod) []*corev1.Pod
+	TakeRollback(*corev1.Pod) (string, bool)
 }
 
 // PodGroupManager defines the scheduling operation called
@@ -59,66 +80,136 @@ type PodGroupManager struct {
 	// scheduleTimeout is the default time when group scheduling.
 	// If podgroup's ScheduleTimeoutSeconds set, that would be used.
 	scheduleTimeout *time.Duration
-	// lastDeniedPG stores the pg name if a pod can not pass pre-filer,
+	// backoff tracks the backoff of a pg if a pod can not pass pre-filer,
 	// or anyone of the pod timeout
-	lastDeniedPG *gochache.Cache
+	backoff *PodGroupBackoff
+	// backoffStatuses queues the backoffs to be recorded in the statuses of podGroups.
+	backoffStatuses *backoffStatusQueue
 	// permittedPG stores the pg name which has passed the pre resource check.
 	permittedPG *gochache.Cache
-	// deniedCacheExpirationTime is the expiration time that a podGroup remains in lastDeniedPG store.
-	lastDeniedPGExpirationTime *time.Duration
 	// pgLister is podgroup lister
 	pgLister pglister.PodGroupLister
 	// podLister is pod lister
 	podLister listerv1.PodLister
 	// reserveResourcePercentage is the reserved resource for the max finished group, range (0,100]
 	reserveResourcePercentage int32
+	// reservations stores the nodes reserved for starving podGroups, keyed by the full names of the podGroups.
+	reservations    map[string]*reservation
+	reservationLock sync.RWMutex
+	// lightweightPGs stores the PodGroups declared by pod labels without the PodGroup CRD,
+	// keyed by the full names of the podGroups.
+	lightweightPGs  map[string]*v1alpha1.PodGroup
+	lightweightLock sync.RWMutex
+	// resolver resolves the podGroup of pods from their labels or annotations.
+	resolver *util.PodGroupResolver
+	// assignedPods indexes the pods that have been assigned a node by their podGroups.
+	assignedPods *assignedPodIndex
+	// eventRecorder records the events of podGroups.
+	eventRecorder events.EventRecorder
+	// bindFailedPods stores the namespaced names of the members of permitted podGroups that failed to be bound.
+	bindFailedPods sets.String
+	// permittedPods stores the namespaced names of the members that are permitted but not bound yet.
+	permittedPods sets.String
+	// rollbackPods stores the permitted members to be evicted once they are bound, as another member failed
+	// to be bound, keyed by their namespaced names to the names of the failed members.
+	rollbackPods map[string]string
+	// bindFailedLock guards bindFailedPods, permittedPods and rollbackPods.
+	bindFailedLock sync.RWMutex
 	sync.RWMutex
 }
 
 // NewPodGroupManager create a new operation object
-func NewPodGroupManager(pgClient pgclientset.Interface, snapshotSharedLister framework.SharedLister, scheduleTimeout, deniedPGExpirationTime *time.Duration,
-	pgInformer pginformer.PodGroupInformer, podInformer informerv1.PodInformer) *PodGroupManager {
+func NewPodGroupManager(pgClient pgclientset.Interface, snapshotSharedLister framework.SharedLister, scheduleTimeout, initialBackoff, maxBackoff *time.Duration,
+	pgInformer pginformer.PodGroupInformer, podInformer informerv1.PodInformer, resolver *util.PodGroupResolver,
+	eventRecorder events.EventRecorder) *PodGroupManager {
 	pgMgr := &PodGroupManager{
-		pgClient:                   pgClient,
-		snapshotSharedLister:       snapshotSharedLister,
-		scheduleTimeout:            scheduleTimeout,
-		lastDeniedPGExpirationTime: deniedPGExpirationTime,
-		pgLister:                   pgInformer.Lister(),
-		podLister:                  podInformer.Lister(),
-		lastDeniedPG:               gochache.New(3*time.Second, 3*time.Second),
-		permittedPG:                gochache.New(3*time.Second, 3*time.Second),
+		pgClient:             pgClient,
+		snapshotSharedLister: snapshotSharedLister,
+		scheduleTimeout:      scheduleTimeout,
+		pgLister:             pgInformer.Lister(),
+		podLister:            podInformer.Lister(),
+		backoff:              NewPodGroupBackoff(*initialBackoff, *maxBackoff, clock.RealClock{}),
+		backoffStatuses:      newBackoffStatusQueue(),
+		permittedPG:          gochache.New(3*time.Second, 3*time.Second),
+		reservations:         make(map[string]*reservation),
+		lightweightPGs:       make(map[string]*v1alpha1.PodGroup),
+		resolver:             resolver,
+		assignedPods:         newAssignedPodIndex(),
+		eventRecorder:        eventRecorder,
+		bindFailedPods:       sets.NewString(),
+		permittedPods:        sets.NewString(),
+		rollbackPods:         make(map[string]string),
 	}
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    pgMgr.onPodAdd,
+		UpdateFunc: pgMgr.onPodUpdate,
+		DeleteFunc: pgMgr.onPodDelete,
+	})
 	return pgMgr
 }
 
 // PreFilter filters out a pod if it
-// 1. belongs to a podgroup that was recently denied or
-// 2. the total number of pods in the podgroup is less than the minimum number of pods
-// that is required to be scheduled.
+// 1. belongs to a podgroup that timed out before it was scheduled or
+// 2. belongs to a podgroup occupied by other workloads than the owners of the pod or
+// 3. belongs to a podgroup that is backing off after a recent failure or
+// 4. belongs to a podgroup whose dependencies have not reached their required phases or
+// 5. the total number of pods in the podgroup, or of any role of the podgroup, is less than
+// the minimum number of pods that is required to be scheduled or
+// 6. the minimum number of pods cannot be packed onto the nodes with their own resource requests or
+// 7. the podgroup already has maxMember pods assigned.
 func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, pod *corev1.Pod) error {
 	klog.V(5).Infof("Pre-filter %v", pod.Name)
 	pgFullName, pg := pgMgr.GetPodGroup(pod)
 	if pg == nil {
 		return nil
 	}
-	if _, ok := pgMgr.lastDeniedPG.Get(pgFullName); ok {
-		err := fmt.Errorf("pod with pgName: %v last failed in 3s, deny", pgFullName)
+	if pg.Status.Phase == v1alpha1.PodGroupTimeout {
+		return fmt.Errorf("pod with pgName: %v timed out before it was scheduled, deny", pgFullName)
+	}
+	// Pods without owners, e.g., created by hand, may join a pod group occupied by a workload.
+	if occupiedBy := util.GetOccupiedBy(pod); len(occupiedBy) != 0 && len(pg.Status.OccupiedBy) != 0 &&
+		occupiedBy != pg.Status.OccupiedBy {
+		return fmt.Errorf("pod with pgName: %v is owned by %q, but the pod group is occupied by %q, deny",
+			pgFullName, occupiedBy, pg.Status.OccupiedBy)
+	}
+	if remaining := pgMgr.backoff.Remaining(pgFullName); remaining > 0 {
+		err := fmt.Errorf("pod with pgName: %v is backing off for %v, deny", pgFullName, remaining)
 		klog.V(6).Info(err)
 		return err
 	}
-	pods, err := pgMgr.podLister.Pods(pod.Namespace).List(
-		labels.SelectorFromSet(labels.Set{util.PodGroupLabel: util.GetPodGroupLabel(pod)}),
-	)
+	if message := util.GetDependencyCycle(pg, pgMgr.pgLister); len(message) != 0 {
+		pgMgr.setUnschedulable(pg, v1alpha1.PodGroupDependencyCycleReason, message)
+		return fmt.Errorf("pre-filter pod %v cannot be scheduled, %v", pod.Name, message)
+	}
+	if message := util.GetUnsatisfiedDependency(pg, pgMgr.pgLister); len(message) != 0 {
+		pgMgr.setUnschedulable(pg, v1alpha1.PodGroupDependenciesNotReadyReason, message)
+		return fmt.Errorf("pre-filter pod %v cannot be scheduled, %v", pod.Name, message)
+	}
+	if pg.Spec.MaxMember != nil {
+		assigned := pgMgr.calculateAssignedPods(pg.Name, pg.Namespace)
+		if assigned >= int(*pg.Spec.MaxMember) {
+			return fmt.Errorf("pre-filter pod %v cannot be scheduled, pod group %v already has maxMember %v pods assigned",
+				pod.Name, pgFullName, *pg.Spec.MaxMember)
+		}
+	}
+	pods, err := pgMgr.GetPodGroupPods(pod)
 	if err != nil {
-		return fmt.Errorf("podLister list pods failed: %v", err)
+		return err
 	}
 	if len(pods) < int(pg.Spec.MinMember) {
+		pgMgr.setUnschedulable(pg, v1alpha1.PodGroupNotEnoughMembersReason,
+			fmt.Sprintf("%v of %v members have been created", len(pods), pg.Spec.MinMember))
 		return fmt.Errorf("pre-filter pod %v cannot find enough sibling pods, "+
 			"current pods number: %v, minMember of group: %v", pod.Name, len(pods), pg.Spec.MinMember)
 	}
-
-	if pg.Spec.MinResources == nil {
-		return nil
+	if len(pg.Spec.Roles) != 0 {
+		counts := util.CountPodsByRole(pg, pods)
+		if role := util.GetUnsatisfiedRole(pg, counts); len(role) != 0 {
+			pgMgr.setUnschedulable(pg, v1alpha1.PodGroupNotEnoughMembersReason,
+				fmt.Sprintf("%v of %v members of role %v have been created", counts[role], getRoleMinMember(pg, role), role))
+			return fmt.Errorf("pre-filter pod %v cannot find enough sibling pods of role %v, "+
+				"current pods number: %v, minMember of role: %v", pod.Name, role, counts[role], getRoleMinMember(pg, role))
+		}
 	}
 
 	// TODO(cwdsuzhou): This resource check may not always pre-catch unschedulable pod group.
@@ -133,20 +224,33 @@ func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, pod *corev1.Pod) er
 		return err
 	}
 
-	minResources := pg.Spec.MinResources.DeepCopy()
-	podQuantity := resource.NewQuantity(int64(pg.Spec.MinMember), resource.DecimalSI)
-	minResources[corev1.ResourcePods] = *podQuantity
-	err = pgMgr.CheckClusterResource(nodes, minResources)
+	if pg.Spec.MinResources != nil {
+		minResources := pg.Spec.MinResources.DeepCopy()
+		podQuantity := resource.NewQuantity(int64(pg.Spec.MinMember), resource.DecimalSI)
+		minResources[corev1.ResourcePods] = *podQuantity
+		err = pgMgr.CheckClusterResource(nodes, minResources)
+		if err != nil {
+			klog.Errorf("PreFilter pod group %v error: %v", pgFullName, err)
+			pgMgr.BackoffPodGroup(pgFullName, v1alpha1.PodGroupInsufficientResourcesReason, err.Error())
+			return err
+		}
+	}
+
+	if !containsPod(pods, pod) {
+		pods = append(pods, pod)
+	}
+	err = pgMgr.CheckPodGroupPacking(nodes, pg, pods)
 	if err != nil {
 		klog.Errorf("PreFilter pod group %v error: %v", pgFullName, err)
-		pgMgr.AddDeniedPodGroup(pgFullName)
+		pgMgr.BackoffPodGroup(pgFullName, v1alpha1.PodGroupInsufficientResourcesReason, err.Error())
 		return err
 	}
 	pgMgr.permittedPG.Add(pgFullName, pgFullName, *pgMgr.scheduleTimeout)
 	return nil
 }
 
-// Permit permits a pod to run, if the minMember match, it would send a signal to chan.
+// Permit permits a pod to run, if the minMember and the minMember of every role match, it would send a signal to chan.
+// Once minMember pods are assigned, further pods of an elastic podgroup are permitted without waiting.
 func (pgMgr *PodGroupManager) Permit(ctx context.Context, pod *corev1.Pod, nodeName string) (bool, error) {
 	pgFullName, pg := pgMgr.GetPodGroup(pod)
 	if pgFullName == "" {
@@ -157,11 +261,18 @@ func (pgMgr *PodGroupManager) Permit(ctx context.Context, pod *corev1.Pod, nodeN
 		return false, fmt.Errorf("PodGroup not found")
 	}
 
-	assigned := pgMgr.calculateAssignedPods(pg.Name, pg.Namespace)
-	// The number of pods that have been assigned nodes is calculated from the snapshot.
-	// The current pod in not included in the snapshot during the current scheduling cycle.
-	ready := int32(assigned)+1 >= pg.Spec.MinMember
+	assignedPods := pgMgr.getAssignedPods(pg.Name, pg.Namespace)
+	// The current pod is normally assumed in Reserve already.
+	if !containsPod(assignedPods, pod) {
+		assignedPods = append(assignedPods, pod)
+	}
+	ready := int32(len(assignedPods)) >= pg.Spec.MinMember
+	if ready && len(pg.Spec.Roles) != 0 {
+		ready = len(util.GetUnsatisfiedRole(pg, util.CountPodsByRole(pg, assignedPods))) == 0
+	}
 	if ready {
+		pgMgr.resetBackoff(pgFullName, pg)
+		pgMgr.releaseReservation(pgFullName, pg)
 		return true, nil
 	}
 	return false, util.ErrorWaiting
@@ -186,87 +297,115 @@ func (pgMgr *PodGroupManager) PostBind(ctx context.Context, pod *corev1.Pod, nod
 			pgCopy.Status.ScheduleStartTime = metav1.Time{Time: time.Now()}
 		}
 	}
-	pg.Status = pgCopy.Status
-	if pgCopy.Status.Phase != pg.Status.Phase {
-		pg, err := pgMgr.pgLister.PodGroups(pgCopy.Namespace).Get(pgCopy.Name)
-		if err != nil {
-			klog.Error(err)
-			return
-		}
-		patch, err := util.CreateMergePatch(pg, pgCopy)
+	// The podGroup from the cache is shared with other goroutines, so it is patched instead of modified in place.
+	// Lightweight podGroups are patched in memory, and the others only when the phase changes.
+	patch, err := util.CreateMergePatch(pg, pgCopy)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	if ok, err := pgMgr.patchLightweightPodGroup(pg.Namespace, pg.Name, patch); ok {
 		if err != nil {
 			klog.Error(err)
-			return
-		}
-		if err := pgMgr.PatchPodGroup(pg.Name, pg.Namespace, patch); err != nil {
-			klog.Error(err)
-			return
 		}
-		pg.Status.Phase = pgCopy.Status.Phase
+		return
+	}
+	if pgCopy.Status.Phase == pg.Status.Phase {
+		return
+	}
+	if err := pgMgr.PatchPodGroup(pg.Name, pg.Namespace, patch); err != nil {
+		klog.Error(err)
 	}
-	pg.Status.Scheduled = pgCopy.Status.Scheduled
-	return
 }
 
 // GetCreationTimestamp returns the creation time of a podGroup or a pod.
 func (pgMgr *PodGroupManager) GetCreationTimestamp(pod *corev1.Pod, ts time.Time) time.Time {
-	pgName := util.GetPodGroupLabel(pod)
-	if len(pgName) == 0 {
-		return ts
-	}
-	pg, err := pgMgr.pgLister.PodGroups(pod.Namespace).Get(pgName)
-	if err != nil {
+	_, pg := pgMgr.GetPodGroup(pod)
+	if pg == nil {
 		return ts
 	}
 	return pg.CreationTimestamp.Time
 }
 
-// AddDeniedPodGroup adds a podGroup that fails to be scheduled to a PodGroup cache with expriration.
-func (pgMgr *PodGroupManager) AddDeniedPodGroup(pgFullName string) {
-	pgMgr.lastDeniedPG.Add(pgFullName, "", *pgMgr.lastDeniedPGExpirationTime)
+// BackoffPodGroup backs off a podGroup that fails to be scheduled, and queues the backoff to be recorded
+// in its status. If a reason is given, it is recorded in the Scheduled condition of the podGroup as well.
+func (pgMgr *PodGroupManager) BackoffPodGroup(pgFullName, reason, message string) {
+	backoff := pgMgr.backoff.Backoff(pgFullName)
+	pgMgr.backoffStatuses.add(pgFullName, backoffStatus{backoffSeconds: int32(backoff / time.Second), reason: reason, message: message})
+}
+
+// resetBackoff resets the backoff of a podGroup that is permitted, and queues the backoff to be cleared in its status.
+func (pgMgr *PodGroupManager) resetBackoff(pgFullName string, pg *v1alpha1.PodGroup) {
+	if pgMgr.backoff.Reset(pgFullName) || pg.Status.BackoffSeconds != 0 {
+		pgMgr.backoffStatuses.add(pgFullName, backoffStatus{})
+	}
 }
 
-// PatchPodGroup patches a podGroup.
+// PatchPodGroup patches the status of a podGroup. Lightweight podGroups are patched in memory.
 func (pgMgr *PodGroupManager) PatchPodGroup(pgName string, namespace string, patch []byte) error {
 	if len(patch) == 0 {
 		return nil
 	}
+	if ok, err := pgMgr.patchLightweightPodGroup(namespace, pgName, patch); ok {
+		return err
+	}
 	_, err := pgMgr.pgClient.SchedulingV1alpha1().PodGroups(namespace).Patch(context.TODO(), pgName,
-		types.MergePatchType, patch, metav1.PatchOptions{})
+		types.MergePatchType, patch, metav1.PatchOptions{}, "status")
 	return err
 }
 
-// GetPodGroup returns the PodGroup that a Pod belongs to from cache.
+// GetPodGroup returns the PodGroup that a Pod belongs to from cache. If there is no such PodGroup object,
+// the lightweight PodGroup declared by the labels or annotations of the pod is returned.
 func (pgMgr *PodGroupManager) GetPodGroup(pod *corev1.Pod) (string, *v1alpha1.PodGroup) {
-	pgName := util.GetPodGroupLabel(pod)
+	pgName := pgMgr.resolver.GetPodGroupName(pod)
 	if len(pgName) == 0 {
 		return "", nil
 	}
+	pgFullName := fmt.Sprintf("%v/%v", pod.Namespace, pgName)
 	pg, err := pgMgr.pgLister.PodGroups(pod.Namespace).Get(pgName)
 	if err != nil {
-		return fmt.Sprintf("%v/%v", pod.Namespace, pgName), nil
+		if pg := pgMgr.getLightweightPodGroup(pod, pgFullName); pg != nil {
+			return pgFullName, pg
+		}
+		return pgFullName, nil
 	}
-	return fmt.Sprintf("%v/%v", pod.Namespace, pgName), pg
+	return pgFullName, pg
 }
 
-// calculateAssignedPods returns the number of pods that has been assigned a node: assumed or bound.
-func (pgMgr *PodGroupManager) calculateAssignedPods(podGroupName, namespace string) int {
-	nodeInfos, err := pgMgr.snapshotSharedLister.NodeInfos().List()
+// getRoleMinMember returns the minMember of the given role of the pg.
+func getRoleMinMember(pg *v1alpha1.PodGroup, roleName string) int32 {
+	for _, role := range pg.Spec.Roles {
+		if role.Name == roleName {
+			return role.MinMember
+		}
+	}
+	return 0
+}
+
+// GetPodGroupPods returns all pods that belong to the same PodGroup as the given pod.
+func (pgMgr *PodGroupManager) GetPodGroupPods(pod *corev1.Pod) ([]*corev1.Pod, error) {
+	pods, err := pgMgr.podLister.Pods(pod.Namespace).List(labels.Everything())
 	if err != nil {
-		klog.Errorf("Cannot get nodeInfos from frameworkHandle: %v", err)
-		return 0
-	}
-	var count int
-	for _, nodeInfo := range nodeInfos {
-		for _, podInfo := range nodeInfo.Pods {
-			pod := podInfo.Pod
-			if pod.Labels[util.PodGroupLabel] == podGroupName && pod.Namespace == namespace && pod.Spec.NodeName != "" {
-				count++
-			}
+		return nil, fmt.Errorf("podLister list pods failed: %v", err)
+	}
+	pgName := pgMgr.resolver.GetPodGroupName(pod)
+	var members []*corev1.Pod
+	for _, p := range pods {
+		if pgMgr.resolver.GetPodGroupName(p) == pgName {
+			members = append(members, p)
 		}
 	}
+	return members, nil
+}
 
-	return count
+// calculateAssignedPods returns the number of pods that has been assigned a node: assumed or bound.
+func (pgMgr *PodGroupManager) calculateAssignedPods(podGroupName, namespace string) int {
+	return pgMgr.assignedPods.count(fmt.Sprintf("%v/%v", namespace, podGroupName))
+}
+
+// getAssignedPods returns the pods that has been assigned a node: assumed or bound.
+func (pgMgr *PodGroupManager) getAssignedPods(podGroupName, namespace string) []*corev1.Pod {
+	return pgMgr.assignedPods.list(fmt.Sprintf("%v/%v", namespace, podGroupName))
 }
 
 func (pgMgr *PodGroupManager) CheckClusterResource(nodeList []*framework.NodeInfo, resourceRequest corev1.ResourceList) error {
@@ -291,6 +430,60 @@ func (pgMgr *PodGroupManager) CheckClusterResource(nodeList []*framework.NodeInf
 	return fmt.Errorf("resource gap: %v", resourceRequest)
 }
 
+// CheckPodGroupPacking simulates placing the members of a PodGroup that are not assigned yet onto the nodes,
+// one member at a time with its own resource request, until `minMember` members are assigned.
+// Members are packed first-fit in decreasing order of their requests, and the members with the smallest
+// requests are chosen if there are more members than needed.
+// An error with the resource gap is returned if the members cannot be packed.
+// The assigned members are looked up in the index of assigned pods, and already occupy the free resources of the nodes.
+func (pgMgr *PodGroupManager) CheckPodGroupPacking(nodeList []*framework.NodeInfo, pg *v1alpha1.PodGroup, members []*corev1.Pod) error {
+	assigned := sets.NewString()
+	for _, pod := range pgMgr.getAssignedPods(pg.Name, pg.Namespace) {
+		assigned.Insert(string(pod.UID))
+	}
+	var free []*framework.Resource
+	for _, info := range nodeList {
+		if info == nil || info.Node() == nil {
+			continue
+		}
+		free = append(free, getNodeResource(info))
+	}
+
+	var requests []*framework.Resource
+	for _, pod := range members {
+		if assigned.Has(string(pod.UID)) || len(pod.Spec.NodeName) != 0 {
+			continue
+		}
+		requests = append(requests, util.ComputePodResourceRequest(pod))
+	}
+	need := int(pg.Spec.MinMember) - assigned.Len()
+	if need <= 0 {
+		return nil
+	}
+	if need > len(requests) {
+		need = len(requests)
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return lessResource(requests[i], requests[j]) })
+	requests = requests[:need]
+
+	for i := len(requests) - 1; i >= 0; i-- {
+		request := requests[i]
+		packed := false
+		for _, nodeResource := range free {
+			if fitCount(nodeResource, request) > 0 {
+				subtractResource(nodeResource, request)
+				packed = true
+				break
+			}
+		}
+		if !packed {
+			return fmt.Errorf("resource gap: %v of %v members of the pod group cannot be packed onto nodes, "+
+				"a member requesting %v does not fit any node", i+1, need, request.ResourceList())
+		}
+	}
+	return nil
+}
+
 // GetNamespacedName returns the namespaced name
 func GetNamespacedName(obj metav1.Object) string {
 	return fmt.Sprintf("%v/%v", obj.GetNamespace(), obj.GetName())
@@ -319,3 +512,41 @@ func getNodeResource(info *framework.NodeInfo) *framework.Resource {
 	klog.V(4).Infof("Node %v left resource %+v", info.Node().Name, leftResource)
 	return &leftResource
 }
+
+// subtractResource subtracts the request of a pod from the free resource of a node.
+func subtractResource(free, request *framework.Resource) {
+	free.AllowedPodNumber--
+	free.MilliCPU -= request.MilliCPU
+	free.Memory -= request.Memory
+	free.EphemeralStorage -= request.EphemeralStorage
+	for name, quant := range request.ScalarResources {
+		free.ScalarResources[name] -= quant
+	}
+}
+
+// lessResource compares resources by scalar resources (e.g., GPUs) first, then CPU, then memory.
+func lessResource(r1, r2 *framework.Resource) bool {
+	var scalar1, scalar2 int64
+	for _, quant := range r1.ScalarResources {
+		scalar1 += quant
+	}
+	for _, quant := range r2.ScalarResources {
+		scalar2 += quant
+	}
+	if scalar1 != scalar2 {
+		return scalar1 < scalar2
+	}
+	if r1.MilliCPU != r2.MilliCPU {
+		return r1.MilliCPU < r2.MilliCPU
+	}
+	return r1.Memory < r2.Memory
+}
+
+func containsPod(pods []*corev1.Pod, pod *corev1.Pod) bool {
+	for _, p := range pods {
+		if p.UID == pod.UID {
+			return true
+		}
+	}
+	return false
+}
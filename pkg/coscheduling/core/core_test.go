@@ -18,18 +18,21 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	gochache "github.com/patrickmn/go-cache"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	clicache "k8s.io/client-go/tools/cache"
-	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
 	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
 	"sigs.k8s.io/scheduler-plugins/pkg/util"
@@ -38,9 +41,9 @@ import (
 
 func TestPreFilter(t *testing.T) {
 	ctx := context.Background()
-	cs := fakepgclientset.NewSimpleClientset()
+	pgClient := fakepgclientset.NewSimpleClientset()
 
-	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
 	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 	pgInformerFactory.Start(ctx.Done())
 	scheduleTimeout := 10 * time.Second
@@ -51,16 +54,43 @@ func TestPreFilter(t *testing.T) {
 	pgInformer.Informer().GetStore().Add(pg)
 	pgInformer.Informer().GetStore().Add(pg1)
 	pgInformer.Informer().GetStore().Add(pg2)
+	pg4 := testutil.MakePG("pg4", "ns1", 2, nil, nil)
+	pg4.Spec.Roles = []v1alpha1.PodGroupRole{{Name: "launcher", MinMember: 1}, {Name: "worker", MinMember: 1}}
 	pgInformer.Informer().GetStore().Add(pg3)
+	pgInformer.Informer().GetStore().Add(pg4)
+	pg5 := testutil.MakePG("pg5", "ns1", 1, nil, nil)
+	pg5.Status.Phase = v1alpha1.PodGroupScheduled
+	pg6 := testutil.MakePG("pg6", "ns1", 1, nil, nil)
+	pg6.Spec.DependsOn = []v1alpha1.PodGroupDependency{{Name: "pg5"}}
+	pg7 := testutil.MakePG("pg7", "ns1", 1, nil, nil)
+	pg7.Spec.DependsOn = []v1alpha1.PodGroupDependency{{Name: "pg5", Phase: v1alpha1.PodGroupScheduled}}
+	pgInformer.Informer().GetStore().Add(pg5)
+	pgInformer.Informer().GetStore().Add(pg6)
+	pgInformer.Informer().GetStore().Add(pg7)
+	pg8 := testutil.MakePG("pg8", "ns1", 1, nil, nil)
+	pg8.Status.Phase = v1alpha1.PodGroupTimeout
+	pgInformer.Informer().GetStore().Add(pg8)
+	pg9 := testutil.MakePG("pg9", "ns1", 1, nil, nil)
+	pg9.Status.OccupiedBy = "ns1/job-a"
+	pgInformer.Informer().GetStore().Add(pg9)
+	maxMember := int32(2)
+	pg10 := testutil.MakePG("pg10", "ns1", 1, nil, nil)
+	pg10.Spec.MaxMember = &maxMember
+	pgInformer.Informer().GetStore().Add(pg10)
+	ownedBy := func(pod *corev1.Pod, owner string) *corev1.Pod {
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: owner}}
+		return pod
+	}
 	pgLister := pgInformer.Lister()
-	denyCache := newCache()
-	denyCache.SetDefault("ns1/pg1", "ns1/pg1")
+	deniedBackoff := newBackoff()
+	deniedBackoff.Backoff("ns1/pg1")
 
 	tests := []struct {
 		name            string
 		pod             *corev1.Pod
 		pods            []*corev1.Pod
-		lastDeniedPG    *gochache.Cache
+		assignedPods    []*corev1.Pod
+		backoff         *PodGroupBackoff
 		expectedSuccess bool
 	}{
 		{
@@ -70,19 +100,19 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg2").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
 		{
 			name:            "pg was previously denied",
 			pod:             st.MakePod().Name("p1").UID("p1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
-			lastDeniedPG:    denyCache,
+			backoff:         deniedBackoff,
 			expectedSuccess: false,
 		},
 		{
 			name:            "pod belongs to a non-existing pg",
 			pod:             st.MakePod().Name("p2").UID("p2").Namespace("ns1").Label(util.PodGroupLabel, "pg-notexisting").Obj(),
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
 		{
@@ -91,7 +121,7 @@ func TestPreFilter(t *testing.T) {
 			pods: []*corev1.Pod{
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg2").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: false,
 		},
 		{
@@ -101,7 +131,7 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
 		{
@@ -112,7 +142,7 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 				st.MakePod().Name("pg3-1").UID("pg3-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
 		{
@@ -123,7 +153,7 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(util.PodGroupLabel, "pg2").Obj(),
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg2").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
 		{
@@ -134,7 +164,7 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(util.PodGroupLabel, "pg3").Obj(),
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg3").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
 			expectedSuccess: false,
 		},
 		{
@@ -144,9 +174,108 @@ func TestPreFilter(t *testing.T) {
 				st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 				st.MakePod().Name("pg2-1").UID("pg2-1").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
 			},
-			lastDeniedPG:    newCache(),
+			backoff:         newBackoff(),
+			expectedSuccess: true,
+		},
+		{
+			name: "pod count of a role less than minMember of the role",
+			pod:  st.MakePod().Name("p4").UID("p4").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "worker").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("pg4-1").UID("pg4-1").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "worker").Obj(),
+				st.MakePod().Name("pg4-2").UID("pg4-2").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "worker").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: false,
+		},
+		{
+			name: "pod count of every role equal minMember of the role",
+			pod:  st.MakePod().Name("p4").UID("p4").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "worker").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("pg4-1").UID("pg4-1").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "launcher").Obj(),
+				st.MakePod().Name("pg4-2").UID("pg4-2").Namespace("ns1").Label(util.PodGroupLabel, "pg4").Label(util.RoleLabel, "worker").Obj(),
+			},
+			backoff:         newBackoff(),
 			expectedSuccess: true,
 		},
+		{
+			name: "pg depends on a pg that is not running",
+			pod:  st.MakePod().Name("p6").UID("p6").Namespace("ns1").Label(util.PodGroupLabel, "pg6").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p6").UID("p6").Namespace("ns1").Label(util.PodGroupLabel, "pg6").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: false,
+		},
+		{
+			name: "pg depends on a pg that is scheduled",
+			pod:  st.MakePod().Name("p7").UID("p7").Namespace("ns1").Label(util.PodGroupLabel, "pg7").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p7").UID("p7").Namespace("ns1").Label(util.PodGroupLabel, "pg7").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: true,
+		},
+		{
+			name: "pg timed out",
+			pod:  st.MakePod().Name("p8").UID("p8").Namespace("ns1").Label(util.PodGroupLabel, "pg8").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p8").UID("p8").Namespace("ns1").Label(util.PodGroupLabel, "pg8").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: false,
+		},
+		{
+			name: "pod owned by the workload occupying the pg",
+			pod:  ownedBy(st.MakePod().Name("p9").UID("p9").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(), "job-a"),
+			pods: []*corev1.Pod{
+				ownedBy(st.MakePod().Name("p9").UID("p9").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(), "job-a"),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: true,
+		},
+		{
+			name: "pod owned by another workload than the one occupying the pg",
+			pod:  ownedBy(st.MakePod().Name("p10").UID("p10").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(), "job-b"),
+			pods: []*corev1.Pod{
+				ownedBy(st.MakePod().Name("p10").UID("p10").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(), "job-b"),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: false,
+		},
+		{
+			name: "pod without owner in a pg occupied by a workload",
+			pod:  st.MakePod().Name("p11").UID("p11").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p11").UID("p11").Namespace("ns1").Label(util.PodGroupLabel, "pg9").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: true,
+		},
+		{
+			name: "pg has less than maxMember pods assigned",
+			pod:  st.MakePod().Name("p10-3").UID("p10-3").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p10-3").UID("p10-3").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Obj(),
+			},
+			assignedPods: []*corev1.Pod{
+				st.MakePod().Name("p10-1").UID("p10-1").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Node("node1").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: true,
+		},
+		{
+			name: "pg already has maxMember pods assigned",
+			pod:  st.MakePod().Name("p10-3").UID("p10-3").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Obj(),
+			pods: []*corev1.Pod{
+				st.MakePod().Name("p10-3").UID("p10-3").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Obj(),
+			},
+			assignedPods: []*corev1.Pod{
+				st.MakePod().Name("p10-1").UID("p10-1").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Node("node1").Obj(),
+				st.MakePod().Name("p10-2").UID("p10-2").Namespace("ns1").Label(util.PodGroupLabel, "pg10").Node("node2").Obj(),
+			},
+			backoff:         newBackoff(),
+			expectedSuccess: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -155,8 +284,9 @@ func TestPreFilter(t *testing.T) {
 			podInformer := informerFactory.Core().V1().Pods()
 			existingPods, allNodes := testutil.MakeNodesAndPods(map[string]string{"test": "a"}, 60, 30)
 			snapshot := testutil.NewFakeSharedLister(existingPods, allNodes)
-			pgMgr := &PodGroupManager{pgLister: pgLister, lastDeniedPG: tt.lastDeniedPG, permittedPG: newCache(),
-				snapshotSharedLister: snapshot, podLister: podInformer.Lister(), scheduleTimeout: &scheduleTimeout, lastDeniedPGExpirationTime: &scheduleTimeout}
+			pgMgr := &PodGroupManager{pgClient: pgClient, pgLister: pgLister, backoff: tt.backoff, backoffStatuses: newBackoffStatusQueue(),
+				permittedPG: newCache(), snapshotSharedLister: snapshot, podLister: podInformer.Lister(), scheduleTimeout: &scheduleTimeout,
+				resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex()}
 			informerFactory.Start(ctx.Done())
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
@@ -164,6 +294,9 @@ func TestPreFilter(t *testing.T) {
 			for _, p := range tt.pods {
 				podInformer.Informer().GetStore().Add(p)
 			}
+			for _, p := range tt.assignedPods {
+				pgMgr.assignedPods.add(util.DefaultPodGroupResolver.GetPodGroupFullName(p), p)
+			}
 			err := pgMgr.PreFilter(ctx, tt.pod)
 			if (err == nil) != tt.expectedSuccess {
 				t.Errorf("desire %v, get %v", tt.expectedSuccess, err == nil)
@@ -189,12 +322,11 @@ func TestPermit(t *testing.T) {
 	existingPods, allNodes := testutil.MakeNodesAndPods(map[string]string{util.PodGroupLabel: "pg1"}, 1, 1)
 	existingPods[0].Spec.NodeName = allNodes[0].Name
 	existingPods[0].Namespace = "ns1"
-	snapshot := testutil.NewFakeSharedLister(existingPods, allNodes)
 	timeout := 10 * time.Second
 	tests := []struct {
 		name     string
 		pod      *corev1.Pod
-		snapshot framework.SharedLister
+		assigned []*corev1.Pod
 		allow    bool
 	}{
 		{
@@ -210,19 +342,24 @@ func TestPermit(t *testing.T) {
 		{
 			name:     "pod belongs to a pg that doesn't have enough pods",
 			pod:      st.MakePod().Name("p").UID("p").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
-			snapshot: testutil.NewFakeSharedLister([]*corev1.Pod{}, []*corev1.Node{}),
+			assigned: []*corev1.Pod{},
 			allow:    false,
 		},
 		{
 			name:     "pod belongs to a pg that has enough pods",
 			pod:      st.MakePod().Name("p").UID("p").Namespace("ns1").Label(util.PodGroupLabel, "pg1").Obj(),
-			snapshot: snapshot,
+			assigned: existingPods,
 			allow:    true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pgMgr := &PodGroupManager{pgClient: fakeClient, pgLister: pgLister, scheduleTimeout: &timeout, snapshotSharedLister: tt.snapshot}
+			pgMgr := &PodGroupManager{pgClient: fakeClient, pgLister: pgLister, scheduleTimeout: &timeout, backoff: newBackoff(),
+				backoffStatuses: newBackoffStatusQueue(), resolver: util.DefaultPodGroupResolver, assignedPods: newAssignedPodIndex()}
+			for _, p := range tt.assigned {
+				pgMgr.onPodAdd(p)
+			}
+			pgMgr.AssumePod(tt.pod, "test")
 			allow, err := pgMgr.Permit(ctx, tt.pod, "test")
 			if allow != tt.allow {
 				t.Errorf("want %v, but got %v. err: %v", tt.allow, allow, err)
@@ -231,6 +368,96 @@ func TestPermit(t *testing.T) {
 	}
 }
 
+func TestCheckPodGroupPacking(t *testing.T) {
+	gpu := corev1.ResourceName("nvidia.com/gpu")
+	makeNodes := func(num int, gpus string) []*corev1.Node {
+		var nodes []*corev1.Node
+		for i := 0; i < num; i++ {
+			res := map[corev1.ResourceName]string{corev1.ResourceCPU: "8", corev1.ResourcePods: "20", gpu: gpus}
+			nodes = append(nodes, st.MakeNode().Name(fmt.Sprintf("node%d", i)).Capacity(res).Obj())
+		}
+		return nodes
+	}
+	makeMembers := func(pgName string, num int, gpus string) []*corev1.Pod {
+		var pods []*corev1.Pod
+		for i := 0; i < num; i++ {
+			name := fmt.Sprintf("%v-%d", pgName, i)
+			pods = append(pods, st.MakePod().Name(name).UID(name).Namespace("ns1").Label(util.PodGroupLabel, pgName).
+				Req(map[corev1.ResourceName]string{gpu: gpus}).Obj())
+		}
+		return pods
+	}
+	placed := st.MakePod().Name("pg-placed").UID("pg-placed").Namespace("ns1").Label(util.PodGroupLabel, "pg").
+		Req(map[corev1.ResourceName]string{gpu: "4"}).Node("node0").Obj()
+
+	tests := []struct {
+		name            string
+		minMember       int32
+		existingPods    []*corev1.Pod
+		nodes           []*corev1.Node
+		members         []*corev1.Pod
+		expectedSuccess bool
+	}{
+		{
+			name:            "enough aggregated resources, but fragmented across nodes",
+			minMember:       8,
+			nodes:           makeNodes(32, "1"),
+			members:         makeMembers("pg", 8, "4"),
+			expectedSuccess: false,
+		},
+		{
+			name:            "members can be packed",
+			minMember:       8,
+			nodes:           makeNodes(8, "4"),
+			members:         makeMembers("pg", 8, "4"),
+			expectedSuccess: true,
+		},
+		{
+			name:            "more members than minMember, the smallest members are packed",
+			minMember:       2,
+			nodes:           makeNodes(2, "1"),
+			members:         append(makeMembers("pg", 2, "1"), makeMembers("pg-big", 2, "4")...),
+			expectedSuccess: true,
+		},
+		{
+			name:            "placed members are not packed again",
+			minMember:       2,
+			existingPods:    []*corev1.Pod{placed},
+			nodes:           makeNodes(2, "4"),
+			members:         append(makeMembers("pg", 1, "4"), placed),
+			expectedSuccess: true,
+		},
+		{
+			name:            "placed members occupy resources",
+			minMember:       3,
+			existingPods:    []*corev1.Pod{placed},
+			nodes:           makeNodes(2, "4"),
+			members:         append(makeMembers("pg", 2, "4"), placed),
+			expectedSuccess: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := testutil.MakePG("pg", "ns1", tt.minMember, nil, nil)
+			snapshot := testutil.NewFakeSharedLister(tt.existingPods, tt.nodes)
+			nodeInfos, _ := snapshot.NodeInfos().List()
+			pgMgr := &PodGroupManager{snapshotSharedLister: snapshot, resolver: util.DefaultPodGroupResolver,
+				assignedPods: newAssignedPodIndex()}
+			for _, p := range tt.existingPods {
+				pgMgr.onPodAdd(p)
+			}
+			err := pgMgr.CheckPodGroupPacking(nodeInfos, pg, tt.members)
+			if (err == nil) != tt.expectedSuccess {
+				t.Errorf("desire %v, get %v", tt.expectedSuccess, err)
+			}
+		})
+	}
+}
+
 func newCache() *gochache.Cache {
 	return gochache.New(10*time.Second, 10*time.Second)
 }
+
+func newBackoff() *PodGroupBackoff {
+	return NewPodGroupBackoff(10*time.Second, 60*time.Second, clock.RealClock{})
+}
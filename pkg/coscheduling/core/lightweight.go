@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// getLightweightPodGroup returns the in-memory PodGroup declared by the labels or annotations of the pod,
+// creating it on first sight. It returns nil if the pod does not declare the minimum number of members.
+// The minMember of the PodGroup follows the latest pod that is seen. The stored PodGroups are never modified
+// in place, and callers get a copy, as they may read it without the lock.
+func (pgMgr *PodGroupManager) getLightweightPodGroup(pod *corev1.Pod, pgFullName string) *v1alpha1.PodGroup {
+	minAvailable, ok := util.GetPodGroupMinAvailable(pod)
+	if !ok {
+		return nil
+	}
+	pgMgr.lightweightLock.Lock()
+	defer pgMgr.lightweightLock.Unlock()
+	pg, ok := pgMgr.lightweightPGs[pgFullName]
+	if ok && pg.Spec.MinMember == minAvailable {
+		return pg.DeepCopy()
+	}
+	if ok {
+		pg = pg.DeepCopy()
+	} else {
+		creationTime := pod.CreationTimestamp
+		if creationTime.IsZero() {
+			creationTime = metav1.Time{Time: time.Now()}
+		}
+		pg = &v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              pgMgr.resolver.GetPodGroupName(pod),
+				Namespace:         pod.Namespace,
+				CreationTimestamp: creationTime,
+			},
+			Status: v1alpha1.PodGroupStatus{Phase: v1alpha1.PodGroupPending},
+		}
+		klog.V(4).Infof("Created lightweight PodGroup %v with minMember %v", pgFullName, minAvailable)
+	}
+	pg.Spec.MinMember = minAvailable
+	pgMgr.lightweightPGs[pgFullName] = pg
+	return pg.DeepCopy()
+}
+
+// getPodGroup returns the PodGroup with the given name from cache, falling back to lightweight PodGroups.
+func (pgMgr *PodGroupManager) getPodGroup(namespace, name string) (*v1alpha1.PodGroup, error) {
+	pg, err := pgMgr.pgLister.PodGroups(namespace).Get(name)
+	if err == nil {
+		return pg, nil
+	}
+	pgMgr.lightweightLock.RLock()
+	defer pgMgr.lightweightLock.RUnlock()
+	if pg, ok := pgMgr.lightweightPGs[fmt.Sprintf("%v/%v", namespace, name)]; ok {
+		return pg.DeepCopy(), nil
+	}
+	return nil, err
+}
+
+// patchLightweightPodGroup applies the JSON merge patch to the lightweight PodGroup with the given name,
+// as the API server does for the PodGroup CRD. It returns false if there is no such lightweight PodGroup.
+func (pgMgr *PodGroupManager) patchLightweightPodGroup(namespace, name string, patch []byte) (bool, error) {
+	pgMgr.lightweightLock.Lock()
+	defer pgMgr.lightweightLock.Unlock()
+	pg, ok := pgMgr.lightweightPGs[fmt.Sprintf("%v/%v", namespace, name)]
+	if !ok {
+		return false, nil
+	}
+	original, err := json.Marshal(pg)
+	if err != nil {
+		return true, err
+	}
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return true, err
+	}
+	pgCopy := &v1alpha1.PodGroup{}
+	if err := json.Unmarshal(patched, pgCopy); err != nil {
+		return true, err
+	}
+	// The patched PodGroup replaces the stored one, as the callers of GetPodGroup may still read the old one.
+	pgMgr.lightweightPGs[fmt.Sprintf("%v/%v", namespace, name)] = pgCopy
+	return true, nil
+}
+
+// forgetLightweightPodGroup forgets the lightweight PodGroup of a deleted pod once none of its pods are left.
+func (pgMgr *PodGroupManager) forgetLightweightPodGroup(pod *corev1.Pod) {
+	pgName := pgMgr.resolver.GetPodGroupName(pod)
+	if len(pgName) == 0 {
+		return
+	}
+	pods, err := pgMgr.GetPodGroupPods(pod)
+	if err != nil || len(pods) != 0 {
+		return
+	}
+	pgMgr.lightweightLock.Lock()
+	delete(pgMgr.lightweightPGs, fmt.Sprintf("%v/%v", pod.Namespace, pgName))
+	pgMgr.lightweightLock.Unlock()
+}
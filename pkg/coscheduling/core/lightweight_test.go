@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestLightweightPodGroup(t *testing.T) {
+	ctx := context.Background()
+	pgClient := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pg1", "ns1", 3, nil, nil))
+
+	fakeClient := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	informerFactory.Start(ctx.Done())
+
+	scheduleDuration := 10 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
+	pgMgr := NewPodGroupManager(pgClient, testutil.NewFakeSharedLister(nil, nil), &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, util.DefaultPodGroupResolver, nil)
+
+	makePod := func(name, pgName, minAvailable string) *corev1.Pod {
+		pod := st.MakePod().Name(name).UID(name).Namespace("ns1").Label(util.PodGroupLabel, pgName).Obj()
+		if len(minAvailable) != 0 {
+			pod.Labels[util.PodGroupMinAvailableLabel] = minAvailable
+		}
+		return pod
+	}
+
+	// PodGroup objects take precedence over pod labels.
+	if _, pg := pgMgr.GetPodGroup(makePod("p1", "pg1", "2")); pg == nil || pg.Spec.MinMember != 3 {
+		t.Errorf("expected PodGroup pg1 with minMember 3, got %v", pg)
+	}
+	// Pods without the PodGroup object nor min-available do not belong to a PodGroup.
+	if _, pg := pgMgr.GetPodGroup(makePod("p2", "pg2", "")); pg != nil {
+		t.Errorf("expected no PodGroup, got %v", pg)
+	}
+
+	pod := makePod("p2", "pg2", "2")
+	podInformer.Informer().GetStore().Add(pod)
+	pgFullName, pg := pgMgr.GetPodGroup(pod)
+	if pgFullName != "ns1/pg2" || pg == nil || pg.Spec.MinMember != 2 {
+		t.Fatalf("expected lightweight PodGroup ns1/pg2 with minMember 2, got %v, %v", pgFullName, pg)
+	}
+
+	// The minMember follows the latest pod, and the PodGroups returned before are not modified.
+	if _, updated := pgMgr.GetPodGroup(makePod("p3", "pg2", "3")); updated == nil || updated.Spec.MinMember != 3 || pg.Spec.MinMember != 2 {
+		t.Errorf("expected minMember 3 without modifying the returned PodGroup, got %v, %v", updated, pg)
+	}
+	_, pg = pgMgr.GetPodGroup(pod)
+
+	// The status of lightweight PodGroups is kept in memory.
+	pgCopy := pg.DeepCopy()
+	pgCopy.Status.Phase = v1alpha1.PodGroupScheduling
+	patch, err := util.CreateMergePatch(pg, pgCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pgMgr.PatchPodGroup("pg2", "ns1", patch); err != nil {
+		t.Fatal(err)
+	}
+	if _, patched := pgMgr.GetPodGroup(pod); patched.Status.Phase != v1alpha1.PodGroupScheduling || pg.Status.Phase != v1alpha1.PodGroupPending {
+		t.Errorf("expected phase %v without modifying the returned PodGroup, got %v, %v", v1alpha1.PodGroupScheduling,
+			patched.Status.Phase, pg.Status.Phase)
+	}
+
+	// Lightweight PodGroups are forgotten once all of their pods are deleted.
+	podInformer.Informer().GetStore().Delete(pod)
+	pgMgr.onPodDelete(pod)
+	if _, err := pgMgr.getPodGroup("ns1", "pg2"); err == nil {
+		t.Error("expected lightweight PodGroup ns1/pg2 to be forgotten")
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// reservation is the set of nodes reserved for a starving PodGroup.
+type reservation struct {
+	// nodes are the names of the reserved nodes.
+	nodes sets.String
+	// priority is the priority of the PodGroup. Only pods with lower priority are kept off the nodes.
+	priority int32
+	// expireAt is the time when the reservation expires, i.e. the schedule timeout of the PodGroup.
+	expireAt time.Time
+	// threshold is the waiting time of the PodGroup after which it was reserved for.
+	// The PodGroup cannot be reserved for again until it has waited for threshold after expireAt.
+	threshold time.Duration
+}
+
+// ReserveNodes reserves nodes for the PodGroup of the given pod, if the PodGroup has waited
+// for longer than threshold since it started scheduling. The reserved nodes are chosen in the order
+// of their free resources until they can hold the members of the PodGroup which still need to be
+// placed, counting the resources used by pods whose priority is lower than the given priority as free.
+// The reservation lasts until the PodGroup is permitted or its schedule timeout expires.
+func (pgMgr *PodGroupManager) ReserveNodes(pod *corev1.Pod, priority int32, threshold time.Duration) {
+	pgFullName, pg := pgMgr.GetPodGroup(pod)
+	if pg == nil {
+		return
+	}
+	now := time.Now()
+	pgMgr.releaseExpiredReservations(now)
+
+	pgMgr.reservationLock.RLock()
+	_, reserved := pgMgr.reservations[pgFullName]
+	pgMgr.reservationLock.RUnlock()
+	if reserved {
+		return
+	}
+	startTime := pg.Status.ScheduleStartTime.Time
+	if startTime.IsZero() {
+		startTime = pg.CreationTimestamp.Time
+	}
+	if now.Sub(startTime) < threshold {
+		return
+	}
+	pods, err := pgMgr.GetPodGroupPods(pod)
+	if err != nil || len(pods) < int(pg.Spec.MinMember) {
+		return
+	}
+	need := int(pg.Spec.MinMember) - pgMgr.calculateAssignedPods(pg.Name, pg.Namespace)
+	if need <= 0 {
+		return
+	}
+	nodeInfos, err := pgMgr.snapshotSharedLister.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Cannot get nodeInfos from frameworkHandle: %v", err)
+		return
+	}
+	nodes := selectReservedNodes(nodeInfos, util.ComputePodResourceRequest(pod), priority, need)
+	if nodes.Len() == 0 {
+		return
+	}
+
+	pgMgr.reservationLock.Lock()
+	pgMgr.reservations[pgFullName] = &reservation{
+		nodes:     nodes,
+		priority:  priority,
+		expireAt:  now.Add(util.GetWaitTimeDuration(pg, pgMgr.scheduleTimeout)),
+		threshold: threshold,
+	}
+	pgMgr.reservationLock.Unlock()
+	klog.V(3).Infof("Reserved nodes %v for PodGroup %v", nodes.List(), pgFullName)
+	pgMgr.patchReservedNodes(pg, nodes.List())
+}
+
+// GetNodeReservation returns the full name and the priority of the PodGroup that the node is reserved for.
+// An empty name is returned if the node is not reserved.
+func (pgMgr *PodGroupManager) GetNodeReservation(nodeName string) (string, int32) {
+	pgMgr.reservationLock.RLock()
+	defer pgMgr.reservationLock.RUnlock()
+	now := time.Now()
+	for pgFullName, r := range pgMgr.reservations {
+		if now.Before(r.expireAt) && r.nodes.Has(nodeName) {
+			return pgFullName, r.priority
+		}
+	}
+	return "", 0
+}
+
+// releaseReservation releases the nodes reserved for a PodGroup that is permitted.
+func (pgMgr *PodGroupManager) releaseReservation(pgFullName string, pg *v1alpha1.PodGroup) {
+	pgMgr.reservationLock.Lock()
+	_, ok := pgMgr.reservations[pgFullName]
+	delete(pgMgr.reservations, pgFullName)
+	pgMgr.reservationLock.Unlock()
+	if ok || len(pg.Status.ReservedNodes) != 0 {
+		pgMgr.patchReservedNodes(pg, nil)
+	}
+}
+
+// releaseExpiredReservations clears the reserved nodes in the status of PodGroups whose reservations expired,
+// and forgets the reservations once the PodGroups are allowed to be reserved for again.
+func (pgMgr *PodGroupManager) releaseExpiredReservations(now time.Time) {
+	var expired []string
+	pgMgr.reservationLock.Lock()
+	for pgFullName, r := range pgMgr.reservations {
+		if now.Before(r.expireAt) {
+			continue
+		}
+		if r.nodes.Len() != 0 {
+			r.nodes = sets.NewString()
+			expired = append(expired, pgFullName)
+		}
+		if now.Sub(r.expireAt) >= r.threshold {
+			delete(pgMgr.reservations, pgFullName)
+		}
+	}
+	pgMgr.reservationLock.Unlock()
+
+	for _, pgFullName := range expired {
+		namespace, name, err := cache.SplitMetaNamespaceKey(pgFullName)
+		if err != nil {
+			continue
+		}
+		if pg, err := pgMgr.getPodGroup(namespace, name); err == nil {
+			klog.V(3).Infof("Reservation of PodGroup %v expired", pgFullName)
+			pgMgr.patchReservedNodes(pg, nil)
+		}
+	}
+}
+
+// patchReservedNodes patches status.reservedNodes of a podGroup if it changes.
+func (pgMgr *PodGroupManager) patchReservedNodes(pg *v1alpha1.PodGroup, nodes []string) {
+	if sets.NewString(pg.Status.ReservedNodes...).Equal(sets.NewString(nodes...)) {
+		return
+	}
+	pgCopy := pg.DeepCopy()
+	pgCopy.Status.ReservedNodes = nodes
+	patch, err := util.CreateMergePatch(pg, pgCopy)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+	if err := pgMgr.PatchPodGroup(pg.Name, pg.Namespace, patch); err != nil {
+		klog.Errorf("Failed to patch reserved nodes of PodGroup %v/%v: %v", pg.Namespace, pg.Name, err)
+	}
+}
+
+// selectReservedNodes chooses nodes that can hold need pods with the given request, preferring nodes
+// with more free resources. Resources used by pods with lower priority than the given priority are
+// counted as available, as those pods will be kept off the reserved nodes once they leave.
+func selectReservedNodes(nodeInfos []*framework.NodeInfo, request *framework.Resource, priority int32, need int) sets.String {
+	type candidate struct {
+		name      string
+		free      int
+		available int
+	}
+	var candidates []candidate
+	for _, info := range nodeInfos {
+		if info == nil || info.Node() == nil {
+			continue
+		}
+		available := fitCount(getAvailableResource(info, priority), request)
+		if available == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			name:      info.Node().Name,
+			free:      fitCount(getNodeResource(info), request),
+			available: available,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].free != candidates[j].free {
+			return candidates[i].free > candidates[j].free
+		}
+		if candidates[i].available != candidates[j].available {
+			return candidates[i].available > candidates[j].available
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	nodes := sets.NewString()
+	for _, c := range candidates {
+		if need <= 0 {
+			break
+		}
+		nodes.Insert(c.name)
+		need -= c.available
+	}
+	if need > 0 {
+		// The nodes cannot hold the PodGroup even if all lower priority pods leave.
+		return sets.NewString()
+	}
+	return nodes
+}
+
+// getAvailableResource returns the allocatable resource of a node minus the requests of pods
+// whose priority is not lower than the given priority.
+func getAvailableResource(info *framework.NodeInfo, priority int32) *framework.Resource {
+	available := info.Allocatable.Clone()
+	if available.ScalarResources == nil {
+		available.ScalarResources = make(map[corev1.ResourceName]int64)
+	}
+	for _, podInfo := range info.Pods {
+		if podutil.GetPodPriority(podInfo.Pod) >= priority {
+			subtractResource(available, util.ComputePodResourceRequest(podInfo.Pod))
+		}
+	}
+	return available
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestSelectReservedNodes(t *testing.T) {
+	var nodes []*corev1.Node
+	for i := 0; i < 3; i++ {
+		res := map[corev1.ResourceName]string{corev1.ResourceCPU: "4", corev1.ResourcePods: "20"}
+		nodes = append(nodes, st.MakeNode().Name(fmt.Sprintf("node%d", i)).Capacity(res).Obj())
+	}
+	makePod := func(name, nodeName string, priority int32) *corev1.Pod {
+		return st.MakePod().Name(name).UID(name).Namespace("ns1").Priority(priority).Node(nodeName).
+			Req(map[corev1.ResourceName]string{corev1.ResourceCPU: "2"}).Obj()
+	}
+	request := util.ComputePodResourceRequest(st.MakePod().Req(map[corev1.ResourceName]string{corev1.ResourceCPU: "2"}).Obj())
+
+	tests := []struct {
+		name         string
+		existingPods []*corev1.Pod
+		need         int
+		expected     []string
+	}{
+		{
+			name:         "nodes with more free resources are preferred",
+			existingPods: []*corev1.Pod{makePod("p0", "node0", 10), makePod("p1", "node1", 10)},
+			need:         2,
+			expected:     []string{"node2"},
+		},
+		{
+			name: "resources used by lower priority pods are available",
+			existingPods: []*corev1.Pod{makePod("p0", "node0", 1), makePod("p1", "node0", 1),
+				makePod("p2", "node1", 10), makePod("p3", "node1", 10), makePod("p4", "node2", 10), makePod("p5", "node2", 10)},
+			need:     2,
+			expected: []string{"node0"},
+		},
+		{
+			name: "resources used by higher priority pods are not available",
+			existingPods: []*corev1.Pod{makePod("p0", "node0", 10), makePod("p1", "node0", 10),
+				makePod("p2", "node1", 10), makePod("p3", "node1", 10), makePod("p4", "node2", 10)},
+			need:     2,
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := testutil.NewFakeSharedLister(tt.existingPods, nodes)
+			nodeInfos, _ := snapshot.NodeInfos().List()
+			got := selectReservedNodes(nodeInfos, request, 5, tt.need)
+			if !got.Equal(sets.NewString(tt.expected...)) {
+				t.Errorf("want %v, got %v", tt.expected, got.List())
+			}
+		})
+	}
+}
+
+func TestGetNodeReservation(t *testing.T) {
+	pg := testutil.MakePG("pg", "ns1", 2, nil, nil)
+	pgMgr := &PodGroupManager{reservations: map[string]*reservation{
+		"ns1/pg": {nodes: sets.NewString("node1"), priority: 10, expireAt: time.Now().Add(time.Minute), threshold: time.Minute},
+	}}
+	if pgFullName, priority := pgMgr.GetNodeReservation("node1"); pgFullName != "ns1/pg" || priority != 10 {
+		t.Errorf("expected node1 to be reserved for ns1/pg with priority 10, got %q, %v", pgFullName, priority)
+	}
+	if pgFullName, _ := pgMgr.GetNodeReservation("node2"); len(pgFullName) != 0 {
+		t.Errorf("expected node2 not to be reserved, got %q", pgFullName)
+	}
+
+	pgMgr.releaseReservation("ns1/pg", pg)
+	if pgFullName, _ := pgMgr.GetNodeReservation("node1"); len(pgFullName) != 0 {
+		t.Errorf("expected the reservation to be released, got %q", pgFullName)
+	}
+}
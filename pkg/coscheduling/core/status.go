@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// backoffStatus is the backoff of a podGroup to be recorded in its status.
+type backoffStatus struct {
+	// backoffSeconds is the current backoff, zero if the backoff is reset.
+	backoffSeconds int32
+	// reason and message are recorded in the Scheduled condition if the reason is not empty.
+	reason  string
+	message string
+}
+
+// backoffStatusQueue queues the backoffs of podGroups to be recorded in their statuses, so that scheduling
+// cycles do not wait for the API server. Backoffs of the same podGroup are coalesced, and only the latest
+// one is recorded.
+type backoffStatusQueue struct {
+	sync.Mutex
+	// pending stores the latest backoff of each queued podGroup, keyed by the full name of the podGroup.
+	pending map[string]backoffStatus
+	queue   workqueue.Interface
+}
+
+func newBackoffStatusQueue() *backoffStatusQueue {
+	return &backoffStatusQueue{
+		pending: make(map[string]backoffStatus),
+		queue:   workqueue.NewNamed("Coscheduling-backoff-status"),
+	}
+}
+
+// add queues the backoff of a podGroup, replacing the one queued before if any.
+func (q *backoffStatusQueue) add(pgFullName string, status backoffStatus) {
+	q.Lock()
+	q.pending[pgFullName] = status
+	q.Unlock()
+	q.queue.Add(pgFullName)
+}
+
+// take removes the queued backoff of a podGroup.
+func (q *backoffStatusQueue) take(pgFullName string) (backoffStatus, bool) {
+	q.Lock()
+	defer q.Unlock()
+	status, ok := q.pending[pgFullName]
+	delete(q.pending, pgFullName)
+	return status, ok
+}
+
+// Run records the queued backoffs of podGroups in their statuses until stopCh is closed.
+func (pgMgr *PodGroupManager) Run(stopCh <-chan struct{}) {
+	defer pgMgr.backoffStatuses.queue.ShutDown()
+	go wait.Until(func() {
+		for pgMgr.processNextBackoffStatus() {
+		}
+	}, time.Second, stopCh)
+	<-stopCh
+}
+
+// processNextBackoffStatus records the next queued backoff in the status of its podGroup.
+// It returns false if the queue is shut down.
+func (pgMgr *PodGroupManager) processNextBackoffStatus() bool {
+	key, quit := pgMgr.backoffStatuses.queue.Get()
+	if quit {
+		return false
+	}
+	defer pgMgr.backoffStatuses.queue.Done(key)
+
+	pgFullName := key.(string)
+	status, ok := pgMgr.backoffStatuses.take(pgFullName)
+	if !ok {
+		return true
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(pgFullName)
+	if err != nil {
+		klog.Error(err)
+		return true
+	}
+	pg, err := pgMgr.getPodGroup(namespace, name)
+	if err != nil {
+		klog.V(5).Infof("Cannot get PodGroup %v: %v", pgFullName, err)
+		return true
+	}
+	pgMgr.patchBackoff(pg, status.backoffSeconds, status.reason, status.message)
+	return true
+}
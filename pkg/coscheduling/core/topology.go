@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// GetPlacedDomain returns the value of the node label topologyKey of the node on which
+// a member of a PodGroup has been placed: assumed or bound.
+// An empty string is returned if no member has been placed yet.
+func (pgMgr *PodGroupManager) GetPlacedDomain(pg *v1alpha1.PodGroup, topologyKey string) string {
+	for _, pod := range pgMgr.getAssignedPods(pg.Name, pg.Namespace) {
+		nodeInfo, err := pgMgr.snapshotSharedLister.NodeInfos().Get(pod.Spec.NodeName)
+		if err != nil || nodeInfo.Node() == nil {
+			continue
+		}
+		if value, ok := nodeInfo.Node().Labels[topologyKey]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// GetLocalityDomain returns the domain of a node in which members of a PodGroup are counted for locality:
+// the value of the node label topologyKey, or the name of the node if topologyKey is empty.
+// False is returned if the node does not have the label.
+func GetLocalityDomain(node *corev1.Node, topologyKey string) (string, bool) {
+	if len(topologyKey) == 0 {
+		return node.Name, true
+	}
+	value, ok := node.Labels[topologyKey]
+	return value, ok
+}
+
+// CountPlacedMembers returns the number of members of a PodGroup that have been placed, assumed or bound,
+// in each locality domain of the node label topologyKey, or on each node if topologyKey is empty.
+func (pgMgr *PodGroupManager) CountPlacedMembers(pg *v1alpha1.PodGroup, topologyKey string) map[string]int {
+	counts := make(map[string]int)
+	for _, pod := range pgMgr.getAssignedPods(pg.Name, pg.Namespace) {
+		nodeInfo, err := pgMgr.snapshotSharedLister.NodeInfos().Get(pod.Spec.NodeName)
+		if err != nil || nodeInfo.Node() == nil {
+			continue
+		}
+		if domain, ok := GetLocalityDomain(nodeInfo.Node(), topologyKey); ok {
+			counts[domain]++
+		}
+	}
+	return counts
+}
+
+// GetTopologyDomains returns the domains of the node label topologyKey which are preferred
+// by the PodGroup of the given pod:
+// 1. the domain in which members of the PodGroup have already been placed, if any.
+// 2. otherwise, all domains whose free resources can hold the members that still need to be placed,
+// assuming that each member requests as much as the given pod.
+func (pgMgr *PodGroupManager) GetTopologyDomains(pod *corev1.Pod, pg *v1alpha1.PodGroup, topologyKey string) sets.String {
+	domains := sets.NewString()
+	if placed := pgMgr.GetPlacedDomain(pg, topologyKey); len(placed) != 0 {
+		return domains.Insert(placed)
+	}
+
+	nodeInfos, err := pgMgr.snapshotSharedLister.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Cannot get nodeInfos from frameworkHandle: %v", err)
+		return domains
+	}
+	remaining := int(pg.Spec.MinMember) - pgMgr.calculateAssignedPods(pg.Name, pg.Namespace)
+	if remaining < 1 {
+		remaining = 1
+	}
+	podRequest := util.ComputePodResourceRequest(pod)
+	capacity := make(map[string]int)
+	for _, info := range nodeInfos {
+		if info == nil || info.Node() == nil {
+			continue
+		}
+		value, ok := info.Node().Labels[topologyKey]
+		if !ok {
+			continue
+		}
+		capacity[value] += fitCount(getNodeResource(info), podRequest)
+	}
+	for value, count := range capacity {
+		if count >= remaining {
+			domains.Insert(value)
+		}
+	}
+	return domains
+}
+
+// fitCount returns how many pods with the given request can be placed into the free resource.
+func fitCount(free, request *framework.Resource) int {
+	count := int64(free.AllowedPodNumber)
+	fit := func(left, req int64) {
+		if req <= 0 {
+			return
+		}
+		if n := left / req; n < count {
+			count = n
+		}
+	}
+	fit(free.MilliCPU, request.MilliCPU)
+	fit(free.Memory, request.Memory)
+	fit(free.EphemeralStorage, request.EphemeralStorage)
+	for name, req := range request.ScalarResources {
+		fit(free.ScalarResources[name], req)
+	}
+	if count < 0 {
+		return 0
+	}
+	return int(count)
+}
@@ -26,14 +26,19 @@ import (
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
 	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
 
 	"sigs.k8s.io/scheduler-plugins/pkg/apis/config"
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/coscheduling/core"
 	pgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
 	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
@@ -44,11 +49,37 @@ import (
 type Coscheduling struct {
 	frameworkHandler framework.FrameworkHandle
 	pgMgr            core.Manager
-	scheduleTimeout  *time.Duration
+	pcLister         schedulinglisters.PriorityClassLister
+	// priorities caches the priorities of PodGroups compared in Less.
+	priorities *priorityCache
+	// pdbLister lists the PodDisruptionBudgets honored by gang preemption.
+	pdbLister       policylisters.PodDisruptionBudgetLister
+	scheduleTimeout *time.Duration
+	// resolver resolves the PodGroup of pods from their labels or annotations.
+	resolver *util.PodGroupResolver
+	// reservationThreshold is the waiting time of a PodGroup after which nodes are reserved for it.
+	// Reservation is disabled if it is 0.
+	reservationThreshold time.Duration
+	// enableBackfill allows backfill pods to be placed on reserved nodes, and to use the resources held by waiting members.
+	enableBackfill bool
+	// backfills stores the backfill pods that use the resources held by waiting members.
+	backfills *backfillIndex
+	// fit is the NodeResourcesFit plugin whose filter is run by Coscheduling if backfill is enabled.
+	fit *noderesources.Fit
+	// backfillPriorityThreshold is the priority below which pods are backfill pods.
+	backfillPriorityThreshold int32
+	// localityMode favors nodes with more, or fewer, members of the same PodGroup. Disabled if it is empty.
+	localityMode config.LocalityMode
+	// localityTopologyKey is the node label whose domains the members are counted in, or nodes if it is empty.
+	localityTopologyKey string
 }
 
 var _ framework.QueueSortPlugin = &Coscheduling{}
 var _ framework.PreFilterPlugin = &Coscheduling{}
+var _ framework.FilterPlugin = &Coscheduling{}
+var _ framework.PostFilterPlugin = &Coscheduling{}
+var _ framework.PreScorePlugin = &Coscheduling{}
+var _ framework.ScorePlugin = &Coscheduling{}
 var _ framework.PermitPlugin = &Coscheduling{}
 var _ framework.ReservePlugin = &Coscheduling{}
 var _ framework.PostBindPlugin = &Coscheduling{}
@@ -56,8 +87,45 @@ var _ framework.PostBindPlugin = &Coscheduling{}
 const (
 	// Name is the name of the plugin used in Registry and configurations.
 	Name = "Coscheduling"
+
+	// topologyStateKey is the key in CycleState to the required topology domain of a PodGroup.
+	topologyStateKey = "PreFilter" + Name + "Topology"
+	// topologyScoreStateKey is the key in CycleState to the preferred topology domains of a PodGroup.
+	topologyScoreStateKey = "PreScore" + Name + "Topology"
 )
 
+// topologyState computed at PreFilter and used at Filter.
+type topologyState struct {
+	// topologyKey is spec.topologyConstraint.requiredTopologyKey of the PodGroup, empty if not set.
+	topologyKey string
+	// domain is the value of topologyKey of the node on which the first member was placed,
+	// empty if no member has been placed yet.
+	domain string
+}
+
+// Clone the topology state.
+func (s *topologyState) Clone() framework.StateData {
+	return s
+}
+
+// topologyScoreState computed at PreScore and used at Score.
+type topologyScoreState struct {
+	// topologyKeys are the required and preferred topology keys of the PodGroup.
+	topologyKeys []string
+	// domains are the preferred domains of each topology key.
+	domains []sets.String
+	// placed is the number of members placed in each locality domain.
+	placed map[string]int
+	// maxPlaced is the max number of members placed in the locality domains of the nodes to score,
+	// zero if locality is disabled or no member has been placed yet.
+	maxPlaced int
+}
+
+// Clone the topology score state.
+func (s *topologyScoreState) Clone() framework.StateData {
+	return s
+}
+
 // New initializes and returns a new Coscheduling plugin.
 func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin, error) {
 	args, ok := obj.(*config.CoschedulingArgs)
@@ -77,26 +145,54 @@ func New(obj runtime.Object, handle framework.FrameworkHandle) (framework.Plugin
 	if err != nil {
 		klog.Fatalf("ParseSelector failed %+v", err)
 	}
+	resolver := util.NewPodGroupResolver(args.PodGroupKeys)
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(handle.ClientSet(), 0, informers.WithTweakListOptions(func(opt *metav1.ListOptions) {
-		opt.LabelSelector = util.PodGroupLabel
+		opt.LabelSelector = resolver.LabelSelector()
 		opt.FieldSelector = fieldSelector.String()
 	}))
 	podInformer := informerFactory.Core().V1().Pods()
 
 	scheduleTimeDuration := time.Duration(args.PermitWaitingTimeSeconds) * time.Second
-	deniedPGExpirationTime := time.Duration(args.DeniedPGExpirationTimeSeconds) * time.Second
+	initialBackoff := time.Duration(args.PodGroupInitialBackoffSeconds) * time.Second
+	maxBackoff := time.Duration(args.PodGroupMaxBackoffSeconds) * time.Second
 
 	ctx := context.TODO()
 
-	pgMgr := core.NewPodGroupManager(pgClient, handle.SnapshotSharedLister(), &scheduleTimeDuration, &deniedPGExpirationTime, pgInformer, podInformer)
+	pgMgr := core.NewPodGroupManager(pgClient, handle.SnapshotSharedLister(), &scheduleTimeDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, resolver,
+		handle.EventRecorder())
 	plugin := &Coscheduling{
 		frameworkHandler: handle,
 		pgMgr:            pgMgr,
+		pcLister:         handle.SharedInformerFactory().Scheduling().V1().PriorityClasses().Lister(),
+		priorities:       newPriorityCache(),
+		pdbLister:        handle.SharedInformerFactory().Policy().V1beta1().PodDisruptionBudgets().Lister(),
 		scheduleTimeout:  &scheduleTimeDuration,
+		resolver:         resolver,
+
+		reservationThreshold:      time.Duration(args.ReservationThresholdSeconds) * time.Second,
+		enableBackfill:            args.EnableBackfill,
+		backfills:                 newBackfillIndex(),
+		backfillPriorityThreshold: args.BackfillPriorityThreshold,
+		localityMode:              args.LocalityMode,
+		localityTopologyKey:       args.LocalityTopologyKey,
 	}
-	pgInformerFactory.Start(ctx.Done())
+	if args.EnableBackfill {
+		if plugin.fit, err = newFit(args, handle); err != nil {
+			return nil, err
+		}
+	}
+	pgInformer.Informer().AddEventHandler(plugin.priorities.podGroupEventHandler())
+	handle.SharedInformerFactory().Scheduling().V1().PriorityClasses().Informer().AddEventHandler(
+		plugin.priorities.priorityClassEventHandler())
+	go pgMgr.Run(ctx.Done())
 	informerFactory.Start(ctx.Done())
-	if !cache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced, podInformer.Informer().HasSynced) {
+	cacheSyncs := []cache.InformerSynced{podInformer.Informer().HasSynced}
+	// Without the PodGroup CRD, the PodGroup informer is left empty and PodGroups are declared by pod labels only.
+	if !args.DisablePodGroupCRD {
+		pgInformerFactory.Start(ctx.Done())
+		cacheSyncs = append(cacheSyncs, pgInformer.Informer().HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), cacheSyncs...) {
 		klog.Error("Cannot sync caches")
 		return nil, fmt.Errorf("WaitForCacheSync failed")
 	}
@@ -109,31 +205,81 @@ func (cs *Coscheduling) Name() string {
 }
 
 // Less is used to sort pods in the scheduling queue in the following order.
-// 1. Compare the priorities of Pods.
-// 2. Compare the initialization timestamps of PodGroups or Pods.
-// 3. Compare the keys of PodGroups/Pods: <namespace>/<podname>.
+// 1. Compare the priorities of PodGroups or Pods.
+// 2. Members of permitted PodGroups that failed to be bound go first.
+// 3. Compare the initialization timestamps of PodGroups or Pods.
+// 4. Compare the keys of PodGroups or Pods: <namespace>/<pgname> or <namespace>/<podname>,
+// so that members of a PodGroup are kept contiguous in the queue.
+// 5. Compare the keys of Pods: <namespace>/<podname>.
 func (cs *Coscheduling) Less(podInfo1, podInfo2 *framework.QueuedPodInfo) bool {
-	prio1 := podutil.GetPodPriority(podInfo1.Pod)
-	prio2 := podutil.GetPodPriority(podInfo2.Pod)
+	prio1 := cs.getPriority(podInfo1.Pod)
+	prio2 := cs.getPriority(podInfo2.Pod)
 	if prio1 != prio2 {
 		return prio1 > prio2
 	}
+	bindFailed1, bindFailed2 := cs.pgMgr.IsBindFailed(podInfo1.Pod), cs.pgMgr.IsBindFailed(podInfo2.Pod)
+	if bindFailed1 != bindFailed2 {
+		return bindFailed1
+	}
 	creationTime1 := cs.pgMgr.GetCreationTimestamp(podInfo1.Pod, podInfo1.InitialAttemptTimestamp)
 	creationTime2 := cs.pgMgr.GetCreationTimestamp(podInfo2.Pod, podInfo2.InitialAttemptTimestamp)
-	if creationTime1.Equal(creationTime2) {
-		return core.GetNamespacedName(podInfo1.Pod) < core.GetNamespacedName(podInfo2.Pod)
+	if !creationTime1.Equal(creationTime2) {
+		return creationTime1.Before(creationTime2)
+	}
+	key1, key2 := cs.getGroupKey(podInfo1.Pod), cs.getGroupKey(podInfo2.Pod)
+	if key1 != key2 {
+		return key1 < key2
+	}
+	return core.GetNamespacedName(podInfo1.Pod) < core.GetNamespacedName(podInfo2.Pod)
+}
+
+// isBackfillPod returns whether the pod may be backfilled onto nodes reserved for PodGroups.
+// Backfill pods do not belong to any PodGroup, and are either annotated with `backfill.scheduling.sigs.k8s.io: "true"`
+// or have a priority lower than the backfill priority threshold.
+func (cs *Coscheduling) isBackfillPod(pod *v1.Pod) bool {
+	if !cs.enableBackfill || len(cs.resolver.GetPodGroupName(pod)) != 0 {
+		return false
+	}
+	return pod.Annotations[util.BackfillAnnotation] == "true" || podutil.GetPodPriority(pod) < cs.backfillPriorityThreshold
+}
+
+// getGroupKey returns the key of the PodGroup of the pod, or the key of the pod if it does not belong to a PodGroup.
+func (cs *Coscheduling) getGroupKey(pod *v1.Pod) string {
+	if fullName := cs.resolver.GetPodGroupFullName(pod); len(fullName) != 0 {
+		return fullName
 	}
-	return creationTime1.Before(creationTime2)
+	return core.GetNamespacedName(pod)
 }
 
 // PreFilter performs the following validations.
-// 1. Whether the PodGroup that the Pod belongs to is on the deny list.
+// 1. Whether the PodGroup that the Pod belongs to is backing off.
 // 2. Whether the total number of pods in a PodGroup is less than its `minMember`.
+// It also records the required topology domain of the PodGroup, which is used in Filter,
+// and reserves nodes for the PodGroup if it has waited for longer than the reservation threshold.
+// If backfill is enabled, it runs the NodeResourcesFit preFilter for the NodeResourcesFit filter run in Filter.
 func (cs *Coscheduling) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) *framework.Status {
+	if cs.reservationThreshold > 0 {
+		cs.pgMgr.ReserveNodes(pod, cs.getPriority(pod), cs.reservationThreshold)
+	}
 	if err := cs.pgMgr.PreFilter(ctx, pod); err != nil {
 		klog.Error(err)
 		return framework.NewStatus(framework.Unschedulable, err.Error())
 	}
+	s := &topologyState{}
+	if _, pg := cs.pgMgr.GetPodGroup(pod); pg != nil && pg.Spec.TopologyConstraint != nil &&
+		len(pg.Spec.TopologyConstraint.RequiredTopologyKey) != 0 {
+		s.topologyKey = pg.Spec.TopologyConstraint.RequiredTopologyKey
+		s.domain = cs.pgMgr.GetPlacedDomain(pg, s.topologyKey)
+	}
+	state.Write(topologyStateKey, s)
+	if cs.enableBackfill {
+		if status := cs.fit.PreFilter(ctx, state, pod); !status.IsSuccess() {
+			return status
+		}
+	}
+	if cs.isBackfillPod(pod) {
+		state.Write(backfillStateKey, cs.computeBackfillState())
+	}
 	return framework.NewStatus(framework.Success, "")
 }
 
@@ -142,9 +288,120 @@ func (cs *Coscheduling) PreFilterExtensions() framework.PreFilterExtensions {
 	return nil
 }
 
+// Filter filters out nodes reserved for other PodGroups with higher priority unless the pod is a backfill pod,
+// and nodes outside of the required topology domain of the PodGroup, which is decided by the first placed member.
+// If backfill is enabled, it also runs the NodeResourcesFit filter, counting the resources held by members
+// waiting in permit as free for backfill pods.
+func (cs *Coscheduling) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node()
+	if node == nil {
+		return framework.NewStatus(framework.Error, "node not found")
+	}
+	if pgFullName, priority := cs.pgMgr.GetNodeReservation(node.Name); len(pgFullName) != 0 &&
+		pgFullName != cs.resolver.GetPodGroupFullName(pod) && cs.getPriority(pod) < priority && !cs.isBackfillPod(pod) {
+		return framework.NewStatus(framework.Unschedulable,
+			fmt.Sprintf("node(s) were reserved for PodGroup %v", pgFullName))
+	}
+	if cs.enableBackfill {
+		if status := cs.filterResources(ctx, state, pod, nodeInfo); !status.IsSuccess() {
+			return status
+		}
+	}
+	s, err := getTopologyState(state)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	if len(s.topologyKey) == 0 {
+		return framework.NewStatus(framework.Success, "")
+	}
+	value, ok := node.Labels[s.topologyKey]
+	if !ok {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+			fmt.Sprintf("node(s) didn't have the topology key %v required by the PodGroup", s.topologyKey))
+	}
+	if len(s.domain) != 0 && value != s.domain {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+			fmt.Sprintf("node(s) didn't match the topology domain %v=%v of the PodGroup", s.topologyKey, s.domain))
+	}
+	return framework.NewStatus(framework.Success, "")
+}
+
+// PreScore computes, for each topology key of the PodGroup, the domains that can hold the whole PodGroup,
+// and the number of members placed in each locality domain if locality is enabled.
+func (cs *Coscheduling) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	s := &topologyScoreState{}
+	_, pg := cs.pgMgr.GetPodGroup(pod)
+	if pg != nil && pg.Spec.TopologyConstraint != nil {
+		constraint := pg.Spec.TopologyConstraint
+		if len(constraint.RequiredTopologyKey) != 0 {
+			s.topologyKeys = append(s.topologyKeys, constraint.RequiredTopologyKey)
+		}
+		s.topologyKeys = append(s.topologyKeys, constraint.PreferredTopologyKeys...)
+		for _, key := range s.topologyKeys {
+			s.domains = append(s.domains, cs.pgMgr.GetTopologyDomains(pod, pg, key))
+		}
+	}
+	if pg != nil && len(cs.localityMode) != 0 {
+		s.placed = cs.pgMgr.CountPlacedMembers(pg, cs.localityTopologyKey)
+		for _, node := range nodes {
+			if domain, ok := core.GetLocalityDomain(node, cs.localityTopologyKey); ok && s.placed[domain] > s.maxPlaced {
+				s.maxPlaced = s.placed[domain]
+			}
+		}
+	}
+	state.Write(topologyScoreStateKey, s)
+	return framework.NewStatus(framework.Success, "")
+}
+
+// Score favors nodes in the topology domains that can hold the whole PodGroup, and nodes with more (Pack)
+// or fewer (Spread) members of the PodGroup in their locality domains. The topology score is proportional
+// to the number of topology keys for which the node's domain is preferred, the locality score to the number
+// of members in the node's locality domain; the two are averaged if both apply.
+func (cs *Coscheduling) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	s, err := getTopologyScoreState(state)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, err.Error())
+	}
+	if len(s.topologyKeys) == 0 && s.maxPlaced == 0 {
+		return 0, nil
+	}
+	nodeInfo, err := cs.frameworkHandler.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
+	}
+	var score, parts int64
+	if len(s.topologyKeys) != 0 {
+		var matched int64
+		for i, key := range s.topologyKeys {
+			if value, ok := nodeInfo.Node().Labels[key]; ok && s.domains[i].Has(value) {
+				matched++
+			}
+		}
+		score += matched * framework.MaxNodeScore / int64(len(s.topologyKeys))
+		parts++
+	}
+	if s.maxPlaced != 0 {
+		var placed int64
+		if domain, ok := core.GetLocalityDomain(nodeInfo.Node(), cs.localityTopologyKey); ok {
+			placed = int64(s.placed[domain])
+		}
+		if cs.localityMode == config.LocalitySpread {
+			placed = int64(s.maxPlaced) - placed
+		}
+		score += placed * framework.MaxNodeScore / int64(s.maxPlaced)
+		parts++
+	}
+	return score / parts, nil
+}
+
+// ScoreExtensions of the Score plugin.
+func (cs *Coscheduling) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
 // Permit is the functions invoked by the framework at "Permit" extension point.
 func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
-	fullName := util.GetPodGroupFullName(pod)
+	fullName := cs.resolver.GetPodGroupFullName(pod)
 	if len(fullName) == 0 {
 		return framework.NewStatus(framework.Success, ""), 0
 	}
@@ -171,39 +428,75 @@ func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState,
 		return framework.NewStatus(framework.Wait, ""), waitTime
 	}
 
+	cs.evictBackfillPods(ctx, fullName)
 	cs.frameworkHandler.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
-		if util.GetPodGroupFullName(waitingPod.GetPod()) == fullName {
+		if cs.resolver.GetPodGroupFullName(waitingPod.GetPod()) == fullName {
 			klog.V(3).Infof("Permit allows the pod: %v", core.GetNamespacedName(waitingPod.GetPod()))
+			cs.pgMgr.MarkPermitted(waitingPod.GetPod())
 			waitingPod.Allow(cs.Name())
 		}
 	})
 	klog.V(3).Infof("Permit allows the pod: %v", core.GetNamespacedName(pod))
+	cs.pgMgr.MarkPermitted(pod)
 	return framework.NewStatus(framework.Success, ""), 0
 }
 
 // Reserve is the functions invoked by the framework at "reserve" extension point.
+// It records the pod as assigned to the node so that Permit can count it, and records backfill pods
+// that use the resources held by waiting members so that they are evicted once the members are permitted.
 func (cs *Coscheduling) Reserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	cs.pgMgr.AssumePod(pod, nodeName)
+	if cs.isBackfillPod(pod) {
+		cs.reserveBackfillPod(ctx, state, pod, nodeName)
+	}
 	return nil
 }
 
 // Unreserve rejects all other Pods in the PodGroup when one of the pods in the group times out.
+// A member of a permitted PodGroup that fails to be bound is handled by spec.bindFailurePolicy of the PodGroup if set.
 func (cs *Coscheduling) Unreserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
 	pgName, pg := cs.pgMgr.GetPodGroup(pod)
+	// The pod failed to be bound if it was permitted; otherwise it was rejected in permit, e.g. timed out.
+	permitted := pg != nil && cs.pgMgr.IsPermitted(pod)
+	var reason, message string
+	if pg != nil && !permitted {
+		reason = v1alpha1.PodGroupPermitTimeoutReason
+		message = fmt.Sprintf("timed out after waiting %v in permit for %v members to be assigned",
+			util.GetWaitTimeDuration(pg, cs.scheduleTimeout), pg.Spec.MinMember)
+	}
+	cs.pgMgr.ForgetPod(pod)
 	if pg == nil {
+		if cs.backfills != nil {
+			cs.backfills.forgetPod(pod)
+		}
+		return
+	}
+	if permitted && len(pg.Spec.BindFailurePolicy) != 0 {
+		cs.handleBindFailure(pod, pg)
 		return
 	}
 	cs.frameworkHandler.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
-		if waitingPod.GetPod().Namespace == pod.Namespace && waitingPod.GetPod().Labels[util.PodGroupLabel] == pg.Name {
+		if cs.resolver.IsMember(waitingPod.GetPod(), pg.Namespace, pg.Name) {
 			klog.V(3).Infof("Unreserve rejects the pod: %v/%v", pgName, waitingPod.GetPod().Name)
 			waitingPod.Reject(cs.Name())
 		}
 	})
-	cs.pgMgr.AddDeniedPodGroup(pgName)
+	// The resources held by the rejected members are released, so the backfill pods using them are kept.
+	if cs.backfills != nil {
+		cs.backfills.forgetPodGroup(pgName)
+	}
+	cs.pgMgr.BackoffPodGroup(pgName, reason, message)
 }
 
 // PostBind is called after a pod is successfully bound. These plugins are used update PodGroup when pod is bound.
 func (cs *Coscheduling) PostBind(ctx context.Context, _ *framework.CycleState, pod *v1.Pod, nodeName string) {
 	klog.V(5).Infof("PostBind pod: %v", core.GetNamespacedName(pod))
+	if failed, ok := cs.pgMgr.TakeRollback(pod); ok {
+		if _, pg := cs.pgMgr.GetPodGroup(pod); pg != nil {
+			cs.rollbackMember(pod, failed, pg)
+			return
+		}
+	}
 	cs.pgMgr.PostBind(ctx, pod, nodeName)
 }
 
@@ -215,3 +508,31 @@ func (cs *Coscheduling) rejectPod(uid types.UID) {
 	}
 	waitingPod.Reject(Name)
 }
+
+func getTopologyState(cycleState *framework.CycleState) (*topologyState, error) {
+	c, err := cycleState.Read(topologyStateKey)
+	if err != nil {
+		// topologyState doesn't exist, likely PreFilter wasn't invoked.
+		return nil, fmt.Errorf("error reading %q from cycleState: %v", topologyStateKey, err)
+	}
+
+	s, ok := c.(*topologyState)
+	if !ok {
+		return nil, fmt.Errorf("%+v  convert to Coscheduling.topologyState error", c)
+	}
+	return s, nil
+}
+
+func getTopologyScoreState(cycleState *framework.CycleState) (*topologyScoreState, error) {
+	c, err := cycleState.Read(topologyScoreStateKey)
+	if err != nil {
+		// topologyScoreState doesn't exist, likely PreScore wasn't invoked.
+		return nil, fmt.Errorf("error reading %q from cycleState: %v", topologyScoreStateKey, err)
+	}
+
+	s, ok := c.(*topologyScoreState)
+	if !ok {
+		return nil, fmt.Errorf("%+v  convert to Coscheduling.topologyScoreState error", c)
+	}
+	return s, nil
+}
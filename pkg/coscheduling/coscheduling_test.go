@@ -18,19 +18,32 @@ package coscheduling
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/config"
 	_ "sigs.k8s.io/scheduler-plugins/pkg/apis/config/scheme"
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/coscheduling/core"
 	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
 	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
@@ -79,16 +92,24 @@ func TestLess(t *testing.T) {
 		pg := testutil.MakePG(pgInfo.pgNme, pgInfo.ns, 5, &pgInfo.createTime, nil)
 		pgInformer.Informer().GetStore().Add(pg)
 	}
+	pg5 := testutil.MakePG("pg5", "namespace1", 5, &times[2], nil)
+	pg5.Spec.PriorityClassName = "high"
+	pg6 := testutil.MakePG("pg6", "namespace1", 5, &times[2], nil)
+	pgInformer.Informer().GetStore().Add(pg5)
+	pgInformer.Informer().GetStore().Add(pg6)
 
 	fakeClient := clientsetfake.NewSimpleClientset()
 	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
 	podInformer := informerFactory.Core().V1().Pods()
+	pcInformer := informerFactory.Scheduling().V1().PriorityClasses()
 	informerFactory.Start(ctx.Done())
+	pcInformer.Informer().GetStore().Add(&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 1000})
 
 	existingPods, allNodes := testutil.MakeNodesAndPods(map[string]string{"test": "a"}, 60, 30)
 	snapshot := testutil.NewFakeSharedLister(existingPods, allNodes)
 	scheudleDuration := 10 * time.Second
-	deniedPGExpirationTime := 3 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
 	var lowPriority, highPriority = int32(10), int32(100)
 	ns1, ns2 := "namespace1", "namespace2"
 	for _, tt := range []struct {
@@ -254,10 +275,32 @@ func TestLess(t *testing.T) {
 			},
 			expected: true, // p1 should be ahead of p2 in the queue
 		},
+		{
+			name: "p1.priority less than p2.priority, but the priority class of podGroup5 is greater than p2.priority",
+			p1: &framework.QueuedPodInfo{
+				Pod: st.MakePod().Namespace(ns1).Name("pod1").Priority(lowPriority).Label(pgutil.PodGroupLabel, "pg5").Obj(),
+			},
+			p2: &framework.QueuedPodInfo{
+				Pod: st.MakePod().Namespace(ns2).Name("pod2").Priority(highPriority).Obj(),
+			},
+			expected: true, // p1 should be ahead of p2 in the queue
+		},
+		{
+			name: "equal priority and creation time, members of podGroup1 are kept ahead of podGroup6",
+			p1: &framework.QueuedPodInfo{
+				Pod:                     st.MakePod().Namespace(ns1).Name("pod1").Priority(highPriority).Label(pgutil.PodGroupLabel, "pg6").Obj(),
+				InitialAttemptTimestamp: times[0],
+			},
+			p2: &framework.QueuedPodInfo{
+				Pod:                     st.MakePod().Namespace(ns1).Name("pod2").Priority(highPriority).Label(pgutil.PodGroupLabel, "pg1").Obj(),
+				InitialAttemptTimestamp: times[0],
+			},
+			expected: false, // p2 should be ahead of p1 in the queue
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheudleDuration, &deniedPGExpirationTime, pgInformer, podInformer)
-			coscheduling := &Coscheduling{pgMgr: pgMgr}
+			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheudleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+			coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, pcLister: pcInformer.Lister()}
 			if got := coscheduling.Less(tt.p1, tt.p2); got != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, got)
 			}
@@ -265,6 +308,54 @@ func TestLess(t *testing.T) {
 	}
 }
 
+func TestGetPriority(t *testing.T) {
+	ctx := context.Background()
+	cs := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformerFactory.Start(ctx.Done())
+	pg := testutil.MakePG("pg1", "ns1", 1, nil, nil)
+	pg.Spec.PriorityClassName = "high"
+	pgInformer.Informer().GetStore().Add(pg)
+
+	fakeClient := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	pcInformer := informerFactory.Scheduling().V1().PriorityClasses()
+	informerFactory.Start(ctx.Done())
+	pcInformer.Informer().GetStore().Add(&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 1000})
+
+	snapshot := testutil.NewFakeSharedLister(nil, nil)
+	scheduleDuration := 10 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
+	pgMgr := core.NewPodGroupManager(cs, snapshot, &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+	priorities := newPriorityCache()
+	coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, pcLister: pcInformer.Lister(), priorities: priorities}
+	pod := st.MakePod().Namespace("ns1").Name("pod1").Priority(10).Label(pgutil.PodGroupLabel, "pg1").Obj()
+
+	if got := coscheduling.getPriority(pod); got != 1000 {
+		t.Errorf("expected the priority of the PriorityClass 1000, got %v", got)
+	}
+	// The cached priority is used until the PriorityClass changes.
+	pcInformer.Informer().GetStore().Update(&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 2000})
+	if got := coscheduling.getPriority(pod); got != 1000 {
+		t.Errorf("expected the cached priority 1000, got %v", got)
+	}
+	priorities.priorityClassEventHandler().OnUpdate(nil, nil)
+	if got := coscheduling.getPriority(pod); got != 2000 {
+		t.Errorf("expected the updated priority of the PriorityClass 2000, got %v", got)
+	}
+	// The priority of the pod is used once the PodGroup no longer sets a PriorityClass.
+	pgCopy := pg.DeepCopy()
+	pgCopy.Spec.PriorityClassName = ""
+	pgInformer.Informer().GetStore().Update(pgCopy)
+	priorities.podGroupEventHandler().OnUpdate(pg, pgCopy)
+	if got := coscheduling.getPriority(pod); got != 10 {
+		t.Errorf("expected the priority of the pod 10, got %v", got)
+	}
+}
+
 func TestPermit(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -304,11 +395,12 @@ func TestPermit(t *testing.T) {
 	existingPods, allNodes := testutil.MakeNodesAndPods(map[string]string{"test": "a"}, 60, 30)
 	snapshot := testutil.NewFakeSharedLister(existingPods, allNodes)
 	scheudleDuration := 10 * time.Second
-	deniedPGExpirationTime := 3 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheudleDuration, &deniedPGExpirationTime, pgInformer, podInformer)
-			coscheduling := &Coscheduling{pgMgr: pgMgr, frameworkHandler: fakeHandler{}, scheduleTimeout: &scheudleDuration}
+			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheudleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+			coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, frameworkHandler: fakeHandler{}, scheduleTimeout: &scheudleDuration}
 			code, _ := coscheduling.Permit(context.Background(), framework.NewCycleState(), tt.pod, "test")
 			if code.Code() != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, code.Code())
@@ -317,17 +409,537 @@ func TestPermit(t *testing.T) {
 	}
 }
 
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	cs := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformerFactory.Start(ctx.Done())
+	pg1 := testutil.MakePG("pg1", "ns1", 2, nil, nil)
+	pg2 := testutil.MakePG("pg2", "ns1", 2, nil, nil)
+	pg2.Spec.TopologyConstraint = &v1alpha1.TopologyConstraint{RequiredTopologyKey: "zone"}
+	pg3 := testutil.MakePG("pg3", "ns1", 2, nil, nil)
+	pg3.Spec.TopologyConstraint = &v1alpha1.TopologyConstraint{RequiredTopologyKey: "zone"}
+	pgInformer.Informer().GetStore().Add(pg1)
+	pgInformer.Informer().GetStore().Add(pg2)
+	pgInformer.Informer().GetStore().Add(pg3)
+
+	fakeClient := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	informerFactory.Start(ctx.Done())
+	for _, name := range []string{"pg1", "pg2", "pg3"} {
+		for i := 0; i < 2; i++ {
+			podInformer.Informer().GetStore().Add(st.MakePod().Name(fmt.Sprintf("%v-%d", name, i)).Namespace("ns1").
+				Label(pgutil.PodGroupLabel, name).Obj())
+		}
+	}
+
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node1").Label("zone", "z1").Obj(),
+		st.MakeNode().Name("node2").Label("zone", "z2").Obj(),
+		st.MakeNode().Name("node3").Obj(),
+	}
+	placed := st.MakePod().Name("pg3-placed").Namespace("ns1").Label(pgutil.PodGroupLabel, "pg3").Node("node1").Obj()
+	snapshot := testutil.NewFakeSharedLister([]*v1.Pod{placed}, nodes)
+	scheduleDuration := 10 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected []framework.Code
+	}{
+		{
+			name:     "pod does not belong to any podGroup",
+			pod:      st.MakePod().Name("p").Namespace("ns1").UID("p").Obj(),
+			expected: []framework.Code{framework.Success, framework.Success, framework.Success},
+		},
+		{
+			name:     "podGroup without topology constraint",
+			pod:      st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			expected: []framework.Code{framework.Success, framework.Success, framework.Success},
+		},
+		{
+			name: "no member has been placed, nodes without topology key are filtered",
+			pod:  st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg2").Obj(),
+			expected: []framework.Code{framework.Success, framework.Success,
+				framework.UnschedulableAndUnresolvable},
+		},
+		{
+			name: "a member has been placed, nodes outside of its domain are filtered",
+			pod:  st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg3").Obj(),
+			expected: []framework.Code{framework.Success, framework.UnschedulableAndUnresolvable,
+				framework.UnschedulableAndUnresolvable},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+			pgMgr.AssumePod(placed, placed.Spec.NodeName)
+			coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, frameworkHandler: fakeHandler{snapshot: snapshot}, scheduleTimeout: &scheduleDuration}
+			state := framework.NewCycleState()
+			if status := coscheduling.PreFilter(ctx, state, tt.pod); !status.IsSuccess() {
+				t.Fatalf("unexpected PreFilter status: %v", status)
+			}
+			for i, node := range nodes {
+				nodeInfo, _ := snapshot.NodeInfos().Get(node.Name)
+				if code := coscheduling.Filter(ctx, state, tt.pod, nodeInfo).Code(); code != tt.expected[i] {
+					t.Errorf("node %v: expected %v, got %v", node.Name, tt.expected[i], code)
+				}
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	ctx := context.Background()
+	cs := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformerFactory.Start(ctx.Done())
+	pg1 := testutil.MakePG("pg1", "ns1", 3, nil, nil)
+	pg2 := testutil.MakePG("pg2", "ns1", 3, nil, nil)
+	pg2.Spec.TopologyConstraint = &v1alpha1.TopologyConstraint{PreferredTopologyKeys: []string{"rack"}}
+	pgInformer.Informer().GetStore().Add(pg1)
+	pgInformer.Informer().GetStore().Add(pg2)
+	placed := []*v1.Pod{
+		st.MakePod().Name("p1").Namespace("ns1").UID("p1").Label(pgutil.PodGroupLabel, "pg1").Node("node1").Obj(),
+		st.MakePod().Name("p2").Namespace("ns1").UID("p2").Label(pgutil.PodGroupLabel, "pg1").Node("node1").Obj(),
+		st.MakePod().Name("p3").Namespace("ns1").UID("p3").Label(pgutil.PodGroupLabel, "pg1").Node("node3").Obj(),
+	}
+
+	fakeClient := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	informerFactory.Start(ctx.Done())
+
+	res := map[v1.ResourceName]string{v1.ResourceCPU: "1", v1.ResourcePods: "20"}
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node1").Label("rack", "r1").Capacity(res).Obj(),
+		st.MakeNode().Name("node2").Label("rack", "r1").Capacity(res).Obj(),
+		st.MakeNode().Name("node3").Label("rack", "r2").Capacity(res).Obj(),
+		st.MakeNode().Name("node4").Label("rack", "r2").Capacity(res).Obj(),
+		st.MakeNode().Name("node5").Label("rack", "r2").Capacity(res).Obj(),
+	}
+	snapshot := testutil.NewFakeSharedLister(nil, nodes)
+	scheduleDuration := 10 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
+
+	tests := []struct {
+		name                string
+		pod                 *v1.Pod
+		placed              []*v1.Pod
+		localityMode        config.LocalityMode
+		localityTopologyKey string
+		expected            []int64
+	}{
+		{
+			name:     "podGroup without topology constraint",
+			pod:      st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			placed:   placed,
+			expected: []int64{0, 0, 0, 0, 0},
+		},
+		{
+			name:         "nodes with more members are favored in pack mode",
+			pod:          st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			placed:       placed,
+			localityMode: config.LocalityPack,
+			expected:     []int64{framework.MaxNodeScore, 0, framework.MaxNodeScore / 2, 0, 0},
+		},
+		{
+			name:                "racks with fewer members are favored in spread mode",
+			pod:                 st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			placed:              placed,
+			localityMode:        config.LocalitySpread,
+			localityTopologyKey: "rack",
+			expected:            []int64{0, 0, framework.MaxNodeScore / 2, framework.MaxNodeScore / 2, framework.MaxNodeScore / 2},
+		},
+		{
+			name:         "no member has been placed in pack mode",
+			pod:          st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			localityMode: config.LocalityPack,
+			expected:     []int64{0, 0, 0, 0, 0},
+		},
+		{
+			name: "only the rack which can hold the whole podGroup is preferred",
+			pod: st.MakePod().Name("p").Namespace("ns1").UID("p").Label(pgutil.PodGroupLabel, "pg2").
+				Req(map[v1.ResourceName]string{v1.ResourceCPU: "1"}).Obj(),
+			expected: []int64{0, 0, framework.MaxNodeScore, framework.MaxNodeScore, framework.MaxNodeScore},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+			coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, frameworkHandler: fakeHandler{snapshot: snapshot}, scheduleTimeout: &scheduleDuration,
+				localityMode: tt.localityMode, localityTopologyKey: tt.localityTopologyKey}
+			for _, pod := range tt.placed {
+				pgMgr.AssumePod(pod, pod.Spec.NodeName)
+			}
+			state := framework.NewCycleState()
+			if status := coscheduling.PreScore(ctx, state, tt.pod, nodes); !status.IsSuccess() {
+				t.Fatalf("unexpected PreScore status: %v", status)
+			}
+			for i, node := range nodes {
+				score, status := coscheduling.Score(ctx, state, tt.pod, node.Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected Score status: %v", status)
+				}
+				if score != tt.expected[i] {
+					t.Errorf("node %v: expected %v, got %v", node.Name, tt.expected[i], score)
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunGangPreemption(t *testing.T) {
+	ctx := context.Background()
+	cs := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformerFactory.Start(ctx.Done())
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pg1", "ns1", 2, nil, nil))
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pg2", "ns1", 1, nil, nil))
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pgv", "ns1", 2, nil, nil))
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pg3", "ns1", 2, nil, nil))
+	pgh := testutil.MakePG("pgh", "ns1", 1, nil, nil)
+	pgh.Spec.PriorityClassName = "high"
+	pgInformer.Informer().GetStore().Add(pgh)
+
+	fakeClient := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	pcInformer := informerFactory.Scheduling().V1().PriorityClasses()
+	informerFactory.Start(ctx.Done())
+	pcInformer.Informer().GetStore().Add(&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 1000})
+
+	highPriority := int32(100)
+	oneCPU := map[v1.ResourceName]string{v1.ResourceCPU: "1"}
+	makeMember := func(name, pgName string) *v1.Pod {
+		return st.MakePod().Name(name).UID(name).Namespace("ns1").Label(pgutil.PodGroupLabel, pgName).
+			Priority(highPriority).Req(oneCPU).Obj()
+	}
+	pg1Pods := []*v1.Pod{makeMember("pg1-0", "pg1"), makeMember("pg1-1", "pg1")}
+	pg2Pod := makeMember("pg2-0", "pg2")
+	pg3Pods := []*v1.Pod{makeMember("pg3-0", "pg3"), makeMember("pg3-1", "pg3")}
+	pg3Pods[1].Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("2")
+	for _, p := range append(append(pg1Pods, pg3Pods...), pg2Pod) {
+		podInformer.Informer().GetStore().Add(p)
+	}
+
+	makeNode := func(name, cpu string) *v1.Node {
+		return st.MakeNode().Name(name).Capacity(map[v1.ResourceName]string{v1.ResourceCPU: cpu, v1.ResourcePods: "10"}).Obj()
+	}
+	makeRunning := func(name, nodeName string, priority int32) *v1.Pod {
+		return st.MakePod().Name(name).UID(name).Namespace("ns1").Node(nodeName).Priority(priority).Req(oneCPU).Obj()
+	}
+	makeBackfill := func(name, nodeName string, priority int32) *v1.Pod {
+		pod := makeRunning(name, nodeName, priority)
+		pod.Annotations = map[string]string{pgutil.BackfillAnnotation: "true"}
+		return pod
+	}
+
+	dbPod := makeRunning("db-1", "node1", 0)
+	dbPod.Labels = map[string]string{"app": "db"}
+	dbPDB := &policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns1"},
+		Spec:       policy.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+	}
+
+	tests := []struct {
+		name            string
+		pod             *v1.Pod
+		pods            []*v1.Pod
+		nodes           []*v1.Node
+		pdbs            []*policy.PodDisruptionBudget
+		expectedVictims []string
+		expectedNodes   []string
+	}{
+		{
+			name:            "victims are evicted on different nodes for all members",
+			pod:             pg1Pods[0],
+			pods:            []*v1.Pod{makeRunning("low-1", "node1", 0), makeRunning("low-2", "node2", 0)},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "1")},
+			expectedVictims: []string{"low-1", "low-2"},
+			expectedNodes:   []string{"node1", "node2"},
+		},
+		{
+			name:  "nothing is evicted if not all members fit",
+			pod:   pg1Pods[0],
+			pods:  []*v1.Pod{makeRunning("low-1", "node1", 0), makeRunning("high-2", "node2", highPriority)},
+			nodes: []*v1.Node{makeNode("node1", "1"), makeNode("node2", "1")},
+		},
+		{
+			name: "victims from a running gang are evicted as a unit",
+			pod:  pg2Pod,
+			pods: []*v1.Pod{
+				st.MakePod().Name("pgv-0").UID("pgv-0").Namespace("ns1").Label(pgutil.PodGroupLabel, "pgv").Node("node1").Req(oneCPU).Obj(),
+				st.MakePod().Name("pgv-1").UID("pgv-1").Namespace("ns1").Label(pgutil.PodGroupLabel, "pgv").Node("node2").Req(oneCPU).Obj(),
+				makeRunning("high-2", "node2", highPriority),
+			},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "2")},
+			expectedVictims: []string{"pgv-0", "pgv-1"},
+			expectedNodes:   []string{"node1"},
+		},
+		{
+			name:            "backfill pods are evicted first regardless of their priority",
+			pod:             pg2Pod,
+			pods:            []*v1.Pod{makeRunning("low-1", "node1", 0), makeBackfill("backfill-2", "node2", highPriority)},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "1")},
+			expectedVictims: []string{"backfill-2"},
+			expectedNodes:   []string{"node2"},
+		},
+		{
+			name:            "lower priority pods are reprieved before backfill pods",
+			pod:             pg2Pod,
+			pods:            []*v1.Pod{makeRunning("low-1", "node1", 0), makeBackfill("backfill-1", "node1", 0)},
+			nodes:           []*v1.Node{makeNode("node1", "2")},
+			expectedVictims: []string{"backfill-1"},
+			expectedNodes:   []string{"node1"},
+		},
+		{
+			name:            "filters are run with the requests of each member",
+			pod:             pg3Pods[0],
+			pods:            []*v1.Pod{makeRunning("low-1", "node1", 0), makeRunning("low-2", "node2", 0), makeRunning("low-3", "node2", 0)},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "2")},
+			expectedVictims: []string{"low-1", "low-2", "low-3"},
+			expectedNodes:   []string{"node1", "node2"},
+		},
+		{
+			name:            "victims violating a PodDisruptionBudget are avoided",
+			pod:             pg2Pod,
+			pods:            []*v1.Pod{dbPod, makeRunning("low-2", "node2", 0)},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "1")},
+			pdbs:            []*policy.PodDisruptionBudget{dbPDB},
+			expectedVictims: []string{"low-2"},
+			expectedNodes:   []string{"node2"},
+		},
+		{
+			name: "members of PodGroups with higher priority are not evicted",
+			pod:  pg2Pod,
+			pods: []*v1.Pod{
+				st.MakePod().Name("pgh-0").UID("pgh-0").Namespace("ns1").Label(pgutil.PodGroupLabel, "pgh").Node("node1").Req(oneCPU).Obj(),
+				makeRunning("low-2", "node2", 0),
+			},
+			nodes:           []*v1.Node{makeNode("node1", "1"), makeNode("node2", "1")},
+			expectedVictims: []string{"low-2"},
+			expectedNodes:   []string{"node2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registeredPlugins := []st.RegisterPluginFunc{
+				st.RegisterPluginAsExtensions(noderesources.FitName, noderesources.NewFit, "Filter", "PreFilter"),
+				st.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+				st.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+			}
+			client := clientsetfake.NewSimpleClientset()
+			snapshot := testutil.NewFakeSharedLister(tt.pods, tt.nodes)
+			fwk, err := st.NewFramework(
+				registeredPlugins,
+				frameworkruntime.WithClientSet(client),
+				frameworkruntime.WithEventRecorder(&events.FakeRecorder{}),
+				frameworkruntime.WithPodNominator(testutil.NewPodNominator()),
+				frameworkruntime.WithSnapshotSharedLister(snapshot),
+				frameworkruntime.WithInformerFactory(informers.NewSharedInformerFactory(client, 0)),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scheduleDuration := 10 * time.Second
+			initialBackoff := 3 * time.Second
+			maxBackoff := 60 * time.Second
+			pgMgr := core.NewPodGroupManager(cs, snapshot, &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+			// The running members are assigned as if they were bound.
+			for _, p := range tt.pods {
+				pgMgr.AssumePod(p, p.Spec.NodeName)
+			}
+			coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, frameworkHandler: fakeHandler{snapshot: snapshot},
+				pcLister: pcInformer.Lister(), scheduleTimeout: &scheduleDuration, enableBackfill: true}
+
+			state := framework.NewCycleState()
+			if status := fwk.RunPreFilterPlugins(ctx, state, tt.pod); !status.IsSuccess() {
+				t.Fatalf("unexpected PreFilter status: %v", status)
+			}
+			_, pg := pgMgr.GetPodGroup(tt.pod)
+			allNodes, _ := snapshot.NodeInfos().List()
+			members, err := coscheduling.pendingMembers(tt.pod, pg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nodesStatuses := framework.NodeToStatusMap{}
+			for _, node := range tt.nodes {
+				nodesStatuses[node.Name] = framework.NewStatus(framework.Unschedulable)
+			}
+
+			plan := coscheduling.dryRunGangPreemption(ctx, fwk, state, tt.pod, members, allNodes, nodesStatuses, tt.pdbs)
+			if plan == nil {
+				if len(tt.expectedNodes) != 0 {
+					t.Fatalf("expected nominated nodes %v, got no plan", tt.expectedNodes)
+				}
+				return
+			}
+			var victims, nodes []string
+			for _, victim := range plan.victims {
+				victims = append(victims, victim.Name)
+			}
+			for _, n := range plan.nominations {
+				nodes = append(nodes, n.nodeName)
+			}
+			sort.Strings(victims)
+			if diff := cmp.Diff(tt.expectedVictims, victims); diff != "" {
+				t.Errorf("unexpected victims (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.expectedNodes, nodes); diff != "" {
+				t.Errorf("unexpected nominated nodes (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestIsBackfillPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		pod      *v1.Pod
+		expected bool
+	}{
+		{
+			name:     "backfill is disabled",
+			pod:      st.MakePod().Name("p").Namespace("ns1").Priority(-1).Obj(),
+			expected: false,
+		},
+		{
+			name:     "priority lower than the threshold",
+			enabled:  true,
+			pod:      st.MakePod().Name("p").Namespace("ns1").Priority(-1).Obj(),
+			expected: true,
+		},
+		{
+			name:     "priority not lower than the threshold",
+			enabled:  true,
+			pod:      st.MakePod().Name("p").Namespace("ns1").Priority(0).Obj(),
+			expected: false,
+		},
+		{
+			name:    "annotated pod",
+			enabled: true,
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns1",
+				Annotations: map[string]string{pgutil.BackfillAnnotation: "true"}}},
+			expected: true,
+		},
+		{
+			name:     "pod belonging to a PodGroup",
+			enabled:  true,
+			pod:      st.MakePod().Name("p").Namespace("ns1").Priority(-1).Label(pgutil.PodGroupLabel, "pg1").Obj(),
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coscheduling := &Coscheduling{enableBackfill: tt.enabled, resolver: pgutil.DefaultPodGroupResolver}
+			if got := coscheduling.isBackfillPod(tt.pod); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+	cs := fakepgclientset.NewSimpleClientset()
+	pgInformerFactory := pgformers.NewSharedInformerFactory(cs, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgInformerFactory.Start(ctx.Done())
+	pgInformer.Informer().GetStore().Add(testutil.MakePG("pg1", "ns1", 2, nil, nil))
+
+	threeCPU := map[v1.ResourceName]string{v1.ResourceCPU: "3"}
+	twoCPU := map[v1.ResourceName]string{v1.ResourceCPU: "2"}
+	member := st.MakePod().Name("pg1-0").UID("pg1-0").Namespace("ns1").Label(pgutil.PodGroupLabel, "pg1").
+		Node("node1").Req(threeCPU).Obj()
+	backfill := st.MakePod().Name("bf").UID("bf").Namespace("ns1").Priority(-1).Req(twoCPU).Obj()
+	regular := st.MakePod().Name("p").UID("p").Namespace("ns1").Req(twoCPU).Obj()
+
+	fakeClient := clientsetfake.NewSimpleClientset(backfill)
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	informerFactory.Start(ctx.Done())
+	podInformer.Informer().GetStore().Add(member)
+	podInformer.Informer().GetStore().Add(st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").
+		Label(pgutil.PodGroupLabel, "pg1").Obj())
+
+	nodes := []*v1.Node{st.MakeNode().Name("node1").Capacity(map[v1.ResourceName]string{v1.ResourceCPU: "4", v1.ResourcePods: "10"}).Obj()}
+	snapshot := testutil.NewFakeSharedLister([]*v1.Pod{member}, nodes)
+	scheduleDuration := 10 * time.Second
+	initialBackoff := 3 * time.Second
+	maxBackoff := 60 * time.Second
+	pgMgr := core.NewPodGroupManager(cs, snapshot, &scheduleDuration, &initialBackoff, &maxBackoff, pgInformer, podInformer, pgutil.DefaultPodGroupResolver, nil)
+	pgMgr.AssumePod(member, member.Spec.NodeName)
+	waitingPod := &fakeWaitingPod{pod: member}
+	recorder := events.NewFakeRecorder(1)
+	handler := fakeHandler{snapshot: snapshot, waitingPods: []*fakeWaitingPod{waitingPod}, clientSet: fakeClient, recorder: recorder}
+	fit, err := newFit(&config.CoschedulingArgs{EnableBackfill: true}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coscheduling := &Coscheduling{pgMgr: pgMgr, resolver: pgutil.DefaultPodGroupResolver, frameworkHandler: handler,
+		scheduleTimeout: &scheduleDuration, enableBackfill: true, backfills: newBackfillIndex(), fit: fit}
+	nodeInfo, _ := snapshot.NodeInfos().Get("node1")
+
+	state := framework.NewCycleState()
+	if status := coscheduling.PreFilter(ctx, state, regular); !status.IsSuccess() {
+		t.Fatalf("unexpected PreFilter status: %v", status)
+	}
+	if code := coscheduling.Filter(ctx, state, regular, nodeInfo).Code(); code != framework.Unschedulable {
+		t.Errorf("expected the regular pod to be unschedulable, got %v", code)
+	}
+
+	state = framework.NewCycleState()
+	if status := coscheduling.PreFilter(ctx, state, backfill); !status.IsSuccess() {
+		t.Fatalf("unexpected PreFilter status: %v", status)
+	}
+	if status := coscheduling.Filter(ctx, state, backfill, nodeInfo); !status.IsSuccess() {
+		t.Fatalf("expected the backfill pod to use the resources held by pg1, got %v", status)
+	}
+	coscheduling.Reserve(ctx, state, backfill, "node1")
+
+	// The second member makes pg1 ready, so the backfill pod is evicted before the members are allowed.
+	second := st.MakePod().Name("pg1-1").UID("pg1-1").Namespace("ns1").Label(pgutil.PodGroupLabel, "pg1").Obj()
+	pgMgr.AssumePod(second, "node2")
+	if status, _ := coscheduling.Permit(ctx, framework.NewCycleState(), second, "node2"); !status.IsSuccess() {
+		t.Fatalf("expected the second member to be permitted, got %v", status)
+	}
+	if !waitingPod.allowed {
+		t.Errorf("expected the waiting member to be allowed")
+	}
+	if _, err := fakeClient.CoreV1().Pods("ns1").Get(ctx, "bf", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the backfill pod to be evicted")
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected an event for the evicted backfill pod, got %d", len(recorder.Events))
+	}
+}
+
 type fakeHandler struct {
+	snapshot    framework.SharedLister
+	waitingPods []*fakeWaitingPod
+	clientSet   kubernetes.Interface
+	recorder    events.EventRecorder
 }
 
 var _ framework.FrameworkHandle = &fakeHandler{}
 
 func (f fakeHandler) SnapshotSharedLister() framework.SharedLister {
-	return nil
+	return f.snapshot
 }
 
 func (f fakeHandler) IterateOverWaitingPods(callback func(framework.WaitingPod)) {
-	return
+	for _, waitingPod := range f.waitingPods {
+		callback(waitingPod)
+	}
 }
 
 func (f fakeHandler) GetWaitingPod(uid types.UID) framework.WaitingPod {
@@ -339,11 +951,11 @@ func (f fakeHandler) RejectWaitingPod(uid types.UID) {
 }
 
 func (f fakeHandler) ClientSet() kubernetes.Interface {
-	return nil
+	return f.clientSet
 }
 
 func (f fakeHandler) EventRecorder() events.EventRecorder {
-	return nil
+	return f.recorder
 }
 
 func (f fakeHandler) SharedInformerFactory() informers.SharedInformerFactory {
@@ -353,3 +965,27 @@ func (f fakeHandler) SharedInformerFactory() informers.SharedInformerFactory {
 func (f fakeHandler) PreemptHandle() framework.PreemptHandle {
 	return nil
 }
+
+type fakeWaitingPod struct {
+	pod      *v1.Pod
+	allowed  bool
+	rejected bool
+}
+
+var _ framework.WaitingPod = &fakeWaitingPod{}
+
+func (w *fakeWaitingPod) GetPod() *v1.Pod {
+	return w.pod
+}
+
+func (w *fakeWaitingPod) GetPendingPlugins() []string {
+	return []string{Name}
+}
+
+func (w *fakeWaitingPod) Allow(pluginName string) {
+	w.allowed = true
+}
+
+func (w *fakeWaitingPod) Reject(msg string) {
+	w.rejected = true
+}
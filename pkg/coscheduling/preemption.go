@@ -0,0 +1,514 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/core"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultpreemption"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// nomination is a member of a PodGroup and the node nominated for it.
+type nomination struct {
+	pod      *v1.Pod
+	nodeName string
+}
+
+// gangPreemptionPlan is the result of a dry-run preemption for a PodGroup.
+type gangPreemptionPlan struct {
+	// victims are the pods to be evicted so that all nominated members fit.
+	victims []*v1.Pod
+	// nominations are the nodes nominated for the members, in the order of members.
+	nominations []nomination
+}
+
+// nodeVictims are the victims to be evicted from a node so that a member fits.
+type nodeVictims struct {
+	pods []*v1.Pod
+	// numPDBViolations is the number of victims whose eviction violates a PodDisruptionBudget.
+	numPDBViolations int
+}
+
+// PostFilter dry-runs preemption for all members of the PodGroup that still need to be placed.
+// If all of them fit after evicting lower-priority victims, the victims are evicted and nodes are
+// nominated for all the members at once. Priorities are those of PodGroups, see getPriority.
+// Victims that belong to a PodGroup are evicted as a unit, so that a running gang is never evicted
+// partially below its minMember. Backfill pods are evicted before any other victims, regardless of
+// their priority, and victims whose eviction violates a PodDisruptionBudget are avoided if possible.
+// Pods that do not belong to a PodGroup are left to other PostFilter plugins, e.g. DefaultPreemption.
+func (cs *Coscheduling) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, m framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	_, pg := cs.pgMgr.GetPodGroup(pod)
+	if pg == nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "pod does not belong to a PodGroup")
+	}
+	nnn, err := cs.preempt(ctx, state, pod, pg, m)
+	if err != nil {
+		return nil, framework.NewStatus(framework.Error, err.Error())
+	}
+	if nnn == "" {
+		return nil, framework.NewStatus(framework.Unschedulable)
+	}
+	return &framework.PostFilterResult{NominatedNodeName: nnn}, framework.NewStatus(framework.Success)
+}
+
+func (cs *Coscheduling) preempt(ctx context.Context, state *framework.CycleState, pod *v1.Pod, pg *v1alpha1.PodGroup, m framework.NodeToStatusMap) (string, error) {
+	client := cs.frameworkHandler.ClientSet()
+	ph := cs.frameworkHandler.PreemptHandle()
+	nodeLister := cs.frameworkHandler.SnapshotSharedLister().NodeInfos()
+	// The PreFilter plugins are run for each member, which the handle does not expose.
+	fwk, ok := cs.frameworkHandler.(framework.Framework)
+	if !ok {
+		return "", fmt.Errorf("want the framework handle to be a framework.Framework, got %T", cs.frameworkHandler)
+	}
+
+	// 0) Fetch the latest version of <pod>.
+	pod, err := util.GetUpdatedPod(client, pod)
+	if err != nil {
+		klog.Errorf("Error getting the updated preemptor pod object: %v", err)
+		return "", err
+	}
+
+	// 1) Ensure the preemptor is eligible to preempt other pods.
+	if !defaultpreemption.PodEligibleToPreemptOthers(pod, nodeLister, m[pod.Status.NominatedNodeName]) {
+		klog.V(5).Infof("Pod %v/%v is not eligible for more preemption.", pod.Namespace, pod.Name)
+		return "", nil
+	}
+
+	// 2) Collect the members of the PodGroup that still need to be placed.
+	allNodes, err := nodeLister.List()
+	if err != nil {
+		return "", err
+	}
+	if len(allNodes) == 0 {
+		return "", core.ErrNoNodesAvailable
+	}
+	members, err := cs.pendingMembers(pod, pg)
+	if err != nil {
+		return "", err
+	}
+	pdbs, err := cs.getPodDisruptionBudgets()
+	if err != nil {
+		return "", err
+	}
+
+	// 3) Dry-run preemption for all members.
+	plan := cs.dryRunGangPreemption(ctx, fwk, state, pod, members, allNodes, m, pdbs)
+	if plan == nil {
+		klog.V(3).Infof("Preemption will not help schedule PodGroup %v/%v.", pg.Namespace, pg.Name)
+		return "", nil
+	}
+
+	// 4) Evict the victims and nominate nodes for the other members.
+	for _, victim := range plan.victims {
+		if err := util.DeletePod(client, victim); err != nil {
+			klog.Errorf("Error preempting pod %v/%v: %v", victim.Namespace, victim.Name, err)
+			return "", err
+		}
+		cs.frameworkHandler.EventRecorder().Eventf(victim, pod, v1.EventTypeNormal, "Preempted", "Preempting",
+			"Preempted by PodGroup %v/%v", pg.Namespace, pg.Name)
+	}
+	var nominatedNodeName string
+	for _, n := range plan.nominations {
+		if n.pod.UID == pod.UID {
+			nominatedNodeName = n.nodeName
+			continue
+		}
+		ph.AddNominatedPod(n.pod, n.nodeName)
+		podCopy := n.pod.DeepCopy()
+		podCopy.Status.NominatedNodeName = n.nodeName
+		if err := util.PatchPod(client, n.pod, podCopy); err != nil {
+			klog.Errorf("Error nominating node %v for pod %v/%v: %v", n.nodeName, n.pod.Namespace, n.pod.Name, err)
+		}
+	}
+	return nominatedNodeName, nil
+}
+
+// pendingMembers returns the given pod followed by other members of the PodGroup which are not
+// assigned yet, so that `minMember` members are assigned once all of them are placed.
+func (cs *Coscheduling) pendingMembers(pod *v1.Pod, pg *v1alpha1.PodGroup) ([]*v1.Pod, error) {
+	assigned := sets.NewString()
+	for _, p := range cs.pgMgr.GetAssignedMembers(pg) {
+		assigned.Insert(string(p.UID))
+	}
+	pods, err := cs.pgMgr.GetPodGroupPods(pod)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	members := []*v1.Pod{pod}
+	need := int(pg.Spec.MinMember) - assigned.Len()
+	for _, p := range pods {
+		if len(members) >= need {
+			break
+		}
+		if p.UID == pod.UID || len(p.Spec.NodeName) != 0 || assigned.Has(string(p.UID)) {
+			continue
+		}
+		members = append(members, p)
+	}
+	return members, nil
+}
+
+// dryRunGangPreemption simulates preemption for the members one by one on copies of the nodes.
+// Each member is placed on the node whose victims violate the fewest PodDisruptionBudgets, then evict
+// the fewest pods other than backfill pods, then the fewest pods in total. The victims are removed from
+// the simulation before the next member is placed.
+// It returns nil if any of the members cannot be placed.
+func (cs *Coscheduling) dryRunGangPreemption(ctx context.Context, fwk framework.Framework, state *framework.CycleState,
+	pod *v1.Pod, members []*v1.Pod, allNodes []*framework.NodeInfo, m framework.NodeToStatusMap,
+	pdbs []*policy.PodDisruptionBudget) *gangPreemptionPlan {
+	nodeInfos := make(map[string]*framework.NodeInfo, len(allNodes))
+	var nodeNames []string
+	for _, nodeInfo := range allNodes {
+		if nodeInfo.Node() == nil {
+			continue
+		}
+		name := nodeInfo.Node().Name
+		// All nodes are kept in the simulation so that running PodGroups are counted in full,
+		// but members are only placed on nodes where preemption might help.
+		nodeInfos[name] = nodeInfo.Clone()
+		if m[name].Code() == framework.UnschedulableAndUnresolvable {
+			continue
+		}
+		nodeNames = append(nodeNames, name)
+	}
+
+	plan := &gangPreemptionPlan{}
+	evicted := sets.NewString()
+	for _, member := range members {
+		memberState, err := cs.memberCycleState(ctx, fwk, state, pod, member, plan, nodeInfos)
+		if err != nil {
+			klog.V(5).Infof("Cannot simulate preemption for member %v/%v of PodGroup: %v", member.Namespace, member.Name, err)
+			return nil
+		}
+		var bestNode string
+		var best *nodeVictims
+		for _, name := range nodeNames {
+			victims, fits := cs.selectVictimsOnNode(ctx, fwk.PreemptHandle(), memberState, pod, member, nodeInfos[name], nodeInfos, pdbs)
+			if !fits {
+				continue
+			}
+			if best == nil || cs.lessVictims(victims, best) {
+				bestNode, best = name, victims
+			}
+			if len(victims.pods) == 0 {
+				break
+			}
+		}
+		if best == nil {
+			klog.V(5).Infof("Member %v/%v of PodGroup cannot be placed even after preemption.", member.Namespace, member.Name)
+			return nil
+		}
+		for _, victim := range best.pods {
+			if evicted.Has(string(victim.UID)) {
+				continue
+			}
+			evicted.Insert(string(victim.UID))
+			if nodeInfo, ok := nodeInfos[victim.Spec.NodeName]; ok {
+				if err := nodeInfo.RemovePod(victim); err != nil {
+					klog.Errorf("Error removing victim %v/%v from the simulation: %v", victim.Namespace, victim.Name, err)
+					return nil
+				}
+			}
+			plan.victims = append(plan.victims, victim)
+		}
+		memberCopy := member.DeepCopy()
+		memberCopy.Spec.NodeName = bestNode
+		nodeInfos[bestNode].AddPod(memberCopy)
+		plan.nominations = append(plan.nominations, nomination{pod: member, nodeName: bestNode})
+	}
+	return plan
+}
+
+// memberCycleState returns the cycle state to run filters for the member. Members may not share the
+// template of the preemptor, so the PreFilter plugins are run for members other than the preemptor,
+// and the victims and members placed so far in the simulation are applied to the state.
+func (cs *Coscheduling) memberCycleState(ctx context.Context, fwk framework.Framework, state *framework.CycleState,
+	pod, member *v1.Pod, plan *gangPreemptionPlan, nodeInfos map[string]*framework.NodeInfo) (*framework.CycleState, error) {
+	memberState := state.Clone()
+	if member.UID != pod.UID {
+		memberState = framework.NewCycleState()
+		if status := fwk.RunPreFilterPlugins(ctx, memberState, member); !status.IsSuccess() {
+			return nil, status.AsError()
+		}
+	}
+	for _, victim := range plan.victims {
+		nodeInfo, ok := nodeInfos[victim.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		if status := fwk.PreemptHandle().RunPreFilterExtensionRemovePod(ctx, memberState, member, victim, nodeInfo); !status.IsSuccess() {
+			return nil, status.AsError()
+		}
+	}
+	for _, n := range plan.nominations {
+		if status := fwk.PreemptHandle().RunPreFilterExtensionAddPod(ctx, memberState, member, n.pod, nodeInfos[n.nodeName]); !status.IsSuccess() {
+			return nil, status.AsError()
+		}
+	}
+	return memberState, nil
+}
+
+// selectVictimsOnNode finds the minimal set of pods in PodGroups with lower priority than the preemptor, or backfill pods,
+// to be evicted from the node so that the member fits. Pods whose eviction violates a PodDisruptionBudget are the first
+// to be reprieved, and backfill pods are the last. The victims are expanded to whole PodGroups where evicting them
+// would bring a PodGroup below its minMember. It returns false if the member cannot fit on the node.
+func (cs *Coscheduling) selectVictimsOnNode(ctx context.Context, ph framework.PreemptHandle, state *framework.CycleState,
+	pod, member *v1.Pod, nodeInfo *framework.NodeInfo, nodeInfos map[string]*framework.NodeInfo,
+	pdbs []*policy.PodDisruptionBudget) (*nodeVictims, bool) {
+	nodeInfoCopy := nodeInfo.Clone()
+	stateCopy := state.Clone()
+	if fits, _, _ := core.PodPassesFiltersOnNode(ctx, ph, stateCopy, member, nodeInfoCopy); fits {
+		return &nodeVictims{}, true
+	}
+
+	removePod := func(rp *v1.Pod) error {
+		if err := nodeInfoCopy.RemovePod(rp); err != nil {
+			return err
+		}
+		status := ph.RunPreFilterExtensionRemovePod(ctx, stateCopy, member, rp, nodeInfoCopy)
+		if !status.IsSuccess() {
+			return status.AsError()
+		}
+		return nil
+	}
+	addPod := func(ap *v1.Pod) error {
+		nodeInfoCopy.AddPod(ap)
+		status := ph.RunPreFilterExtensionAddPod(ctx, stateCopy, member, ap, nodeInfoCopy)
+		if !status.IsSuccess() {
+			return status.AsError()
+		}
+		return nil
+	}
+
+	priority := cs.getPriority(pod)
+	var potentialVictims []*v1.Pod
+	for _, p := range nodeInfoCopy.Pods {
+		if cs.isBackfillPod(p.Pod) ||
+			cs.getPriority(p.Pod) < priority && cs.resolver.GetPodGroupFullName(p.Pod) != cs.resolver.GetPodGroupFullName(pod) {
+			potentialVictims = append(potentialVictims, p.Pod)
+		}
+	}
+	if len(potentialVictims) == 0 {
+		return nil, false
+	}
+	for _, p := range potentialVictims {
+		if err := removePod(p); err != nil {
+			klog.Warningf("Failed to remove pod %v/%v: %v", p.Namespace, p.Name, err)
+			return nil, false
+		}
+	}
+	if fits, _, _ := core.PodPassesFiltersOnNode(ctx, ph, stateCopy, member, nodeInfoCopy); !fits {
+		return nil, false
+	}
+
+	// Try to reprieve as many pods as possible, starting from the pods whose eviction violates a PodDisruptionBudget,
+	// then from the highest priority victims other than backfill pods.
+	result := &nodeVictims{}
+	sort.Slice(potentialVictims, func(i, j int) bool {
+		backfill1, backfill2 := cs.isBackfillPod(potentialVictims[i]), cs.isBackfillPod(potentialVictims[j])
+		if backfill1 != backfill2 {
+			return backfill2
+		}
+		return cs.moreImportantPod(potentialVictims[i], potentialVictims[j])
+	})
+	violatingVictims, nonViolatingVictims := filterPodsWithPDBViolation(potentialVictims, pdbs)
+	reprievePod := func(p *v1.Pod) (bool, error) {
+		if err := addPod(p); err != nil {
+			return false, err
+		}
+		if fits, _, _ := core.PodPassesFiltersOnNode(ctx, ph, stateCopy, member, nodeInfoCopy); !fits {
+			if err := removePod(p); err != nil {
+				return false, err
+			}
+			result.pods = append(result.pods, p)
+			return false, nil
+		}
+		return true, nil
+	}
+	for _, p := range violatingVictims {
+		if reprieved, err := reprievePod(p); err != nil {
+			klog.Warningf("Failed to reprieve pod %v/%v: %v", p.Namespace, p.Name, err)
+			return nil, false
+		} else if !reprieved {
+			result.numPDBViolations++
+		}
+	}
+	for _, p := range nonViolatingVictims {
+		if _, err := reprievePod(p); err != nil {
+			klog.Warningf("Failed to reprieve pod %v/%v: %v", p.Namespace, p.Name, err)
+			return nil, false
+		}
+	}
+	pods, ok := cs.expandVictimsToPodGroups(result.pods, priority, nodeInfos)
+	if !ok {
+		return nil, false
+	}
+	result.pods = pods
+	return result, true
+}
+
+// moreImportantPod returns whether pod1 is in a PodGroup with higher priority than pod2, or started earlier
+// if their priorities are the same.
+func (cs *Coscheduling) moreImportantPod(pod1, pod2 *v1.Pod) bool {
+	p1, p2 := cs.getPriority(pod1), cs.getPriority(pod2)
+	if p1 != p2 {
+		return p1 > p2
+	}
+	return util.GetPodStartTime(pod1).Before(util.GetPodStartTime(pod2))
+}
+
+// getPodDisruptionBudgets returns all PodDisruptionBudgets, or nil if they are not listed.
+func (cs *Coscheduling) getPodDisruptionBudgets() ([]*policy.PodDisruptionBudget, error) {
+	if cs.pdbLister == nil {
+		return nil, nil
+	}
+	return cs.pdbLister.List(labels.Everything())
+}
+
+// filterPodsWithPDBViolation groups the pods into those whose eviction violates a PodDisruptionBudget,
+// assuming that all of them are evicted in the given order, and the others. The order of pods is kept.
+func filterPodsWithPDBViolation(pods []*v1.Pod, pdbs []*policy.PodDisruptionBudget) (violatingPods, nonViolatingPods []*v1.Pod) {
+	pdbsAllowed := make([]int32, len(pdbs))
+	for i, pdb := range pdbs {
+		pdbsAllowed[i] = pdb.Status.DisruptionsAllowed
+	}
+	for _, pod := range pods {
+		violated := false
+		// A pod without labels does not match any PodDisruptionBudget.
+		if len(pod.Labels) != 0 {
+			for i, pdb := range pdbs {
+				if pdb.Namespace != pod.Namespace {
+					continue
+				}
+				selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+				// A PodDisruptionBudget with a nil or empty selector matches nothing.
+				if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+					continue
+				}
+				// Pods being disrupted have been counted by the apiserver already.
+				if _, ok := pdb.Status.DisruptedPods[pod.Name]; ok {
+					continue
+				}
+				pdbsAllowed[i]--
+				if pdbsAllowed[i] < 0 {
+					violated = true
+				}
+			}
+		}
+		if violated {
+			violatingPods = append(violatingPods, pod)
+		} else {
+			nonViolatingPods = append(nonViolatingPods, pod)
+		}
+	}
+	return violatingPods, nonViolatingPods
+}
+
+// lessVictims returns whether victims1 violates fewer PodDisruptionBudgets than victims2, or evicts fewer
+// pods other than backfill pods, or fewer pods in total, in that order.
+func (cs *Coscheduling) lessVictims(victims1, victims2 *nodeVictims) bool {
+	if victims1.numPDBViolations != victims2.numPDBViolations {
+		return victims1.numPDBViolations < victims2.numPDBViolations
+	}
+	others1, others2 := 0, 0
+	for _, p := range victims1.pods {
+		if !cs.isBackfillPod(p) {
+			others1++
+		}
+	}
+	for _, p := range victims2.pods {
+		if !cs.isBackfillPod(p) {
+			others2++
+		}
+	}
+	if others1 != others2 {
+		return others1 < others2
+	}
+	return len(victims1.pods) < len(victims2.pods)
+}
+
+// expandVictimsToPodGroups adds all running members of a PodGroup to the victims, if evicting
+// the victims would bring the PodGroup below its minMember. It returns false if such a PodGroup
+// does not have a lower priority than the preemptor. The running members are the assigned members
+// of the PodGroup that are not evicted in the simulation yet.
+func (cs *Coscheduling) expandVictimsToPodGroups(victims []*v1.Pod, priority int32, nodeInfos map[string]*framework.NodeInfo) ([]*v1.Pod, bool) {
+	result := sets.NewString()
+	var expanded []*v1.Pod
+	add := func(p *v1.Pod) {
+		if !result.Has(string(p.UID)) {
+			result.Insert(string(p.UID))
+			expanded = append(expanded, p)
+		}
+	}
+	victimsPerGroup := make(map[string]int)
+	groups := make(map[string]*v1alpha1.PodGroup)
+	for _, victim := range victims {
+		add(victim)
+		pgFullName, pg := cs.pgMgr.GetPodGroup(victim)
+		if pg == nil {
+			continue
+		}
+		victimsPerGroup[pgFullName]++
+		groups[pgFullName] = pg
+	}
+	for pgFullName, pg := range groups {
+		var members []*v1.Pod
+		for _, p := range cs.pgMgr.GetAssignedMembers(pg) {
+			if onNode(p, nodeInfos[p.Spec.NodeName]) {
+				members = append(members, p)
+			}
+		}
+		if len(members)-victimsPerGroup[pgFullName] >= int(pg.Spec.MinMember) {
+			continue
+		}
+		for _, p := range members {
+			if cs.getPriority(p) >= priority {
+				return nil, false
+			}
+			add(p)
+		}
+	}
+	return expanded, true
+}
+
+// onNode returns whether the pod is on the node.
+func onNode(pod *v1.Pod, nodeInfo *framework.NodeInfo) bool {
+	if nodeInfo == nil {
+		return false
+	}
+	for _, podInfo := range nodeInfo.Pods {
+		if podInfo.Pod.UID == pod.UID {
+			return true
+		}
+	}
+	return false
+}
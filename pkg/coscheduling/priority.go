@@ -0,0 +1,150 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+// groupPriority is the cached priority of a PodGroup.
+type groupPriority struct {
+	// value is the value of the PriorityClass of the PodGroup.
+	value int32
+	// unset is true if the PodGroup does not set a PriorityClass, so the priorities of its pods are used.
+	unset bool
+}
+
+// priorityCache caches the priorities of PodGroups by their full names, as they are compared for every
+// pair of pods in the scheduling queue. The entry of a PodGroup is dropped when the PodGroup is added,
+// updated or deleted, and all entries are dropped when a PriorityClass changes.
+type priorityCache struct {
+	sync.RWMutex
+	priorities map[string]groupPriority
+	// generation is increased whenever entries are dropped, so that a priority computed from
+	// the listers before an update is not cached after the update.
+	generation uint64
+}
+
+func newPriorityCache() *priorityCache {
+	return &priorityCache{priorities: make(map[string]groupPriority)}
+}
+
+// get returns the cached priority of the PodGroup if any, and the current generation of the cache.
+func (c *priorityCache) get(pgFullName string) (groupPriority, bool, uint64) {
+	c.RLock()
+	defer c.RUnlock()
+	p, ok := c.priorities[pgFullName]
+	return p, ok, c.generation
+}
+
+// set caches the priority of the PodGroup, unless entries were dropped since the given generation.
+func (c *priorityCache) set(pgFullName string, p groupPriority, generation uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if c.generation == generation {
+		c.priorities[pgFullName] = p
+	}
+}
+
+// forgetPodGroup drops the entry of an added, updated or deleted PodGroup.
+func (c *priorityCache) forgetPodGroup(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.priorities, key)
+	c.generation++
+}
+
+// forgetAll drops all entries, e.g. when a PriorityClass changes.
+func (c *priorityCache) forgetAll() {
+	c.Lock()
+	defer c.Unlock()
+	c.priorities = make(map[string]groupPriority)
+	c.generation++
+}
+
+// podGroupEventHandler returns the handler that keeps the cache up to date with PodGroups.
+func (c *priorityCache) podGroupEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		// A new PodGroup replaces the lightweight PodGroup of its pods, if any.
+		AddFunc:    c.forgetPodGroup,
+		UpdateFunc: func(_, newObj interface{}) { c.forgetPodGroup(newObj) },
+		DeleteFunc: c.forgetPodGroup,
+	}
+}
+
+// priorityClassEventHandler returns the handler that keeps the cache up to date with PriorityClasses.
+func (c *priorityCache) priorityClassEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.forgetAll() },
+		UpdateFunc: func(_, _ interface{}) { c.forgetAll() },
+		DeleteFunc: func(interface{}) { c.forgetAll() },
+	}
+}
+
+// getPriority returns the value of the PriorityClass in spec.priorityClassName of the PodGroup
+// of the pod, or the priority of the pod if the PodGroup does not set one.
+func (cs *Coscheduling) getPriority(pod *v1.Pod) int32 {
+	pgFullName := cs.resolver.GetPodGroupFullName(pod)
+	if len(pgFullName) == 0 {
+		return podutil.GetPodPriority(pod)
+	}
+	p, ok := cs.getGroupPriority(pod, pgFullName)
+	if !ok || p.unset {
+		return podutil.GetPodPriority(pod)
+	}
+	return p.value
+}
+
+// getGroupPriority returns the priority of the PodGroup of the pod from the cache, or computes and caches it.
+// It returns false if the priority cannot be computed, e.g. the PodGroup or its PriorityClass does not exist.
+func (cs *Coscheduling) getGroupPriority(pod *v1.Pod, pgFullName string) (groupPriority, bool) {
+	var generation uint64
+	if cs.priorities != nil {
+		p, ok, gen := cs.priorities.get(pgFullName)
+		if ok {
+			return p, true
+		}
+		generation = gen
+	}
+
+	_, pg := cs.pgMgr.GetPodGroup(pod)
+	if pg == nil || cs.pcLister == nil {
+		return groupPriority{}, false
+	}
+	p := groupPriority{unset: len(pg.Spec.PriorityClassName) == 0}
+	if !p.unset {
+		pc, err := cs.pcLister.Get(pg.Spec.PriorityClassName)
+		if err != nil {
+			klog.V(5).Infof("Cannot get PriorityClass %v of PodGroup %v/%v: %v", pg.Spec.PriorityClassName, pg.Namespace, pg.Name, err)
+			return groupPriority{}, false
+		}
+		p.value = pc.Value
+	}
+	if cs.priorities != nil {
+		cs.priorities.set(pgFullName, p, generation)
+	}
+	return p, true
+}
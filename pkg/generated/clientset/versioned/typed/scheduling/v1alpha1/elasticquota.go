@@ -40,6 +40,7 @@ type ElasticQuotasGetter interface {
 type ElasticQuotaInterface interface {
 	Create(ctx context.Context, elasticQuota *v1alpha1.ElasticQuota, opts v1.CreateOptions) (*v1alpha1.ElasticQuota, error)
 	Update(ctx context.Context, elasticQuota *v1alpha1.ElasticQuota, opts v1.UpdateOptions) (*v1alpha1.ElasticQuota, error)
+	UpdateStatus(ctx context.Context, elasticQuota *v1alpha1.ElasticQuota, opts v1.UpdateOptions) (*v1alpha1.ElasticQuota, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.ElasticQuota, error)
@@ -135,6 +136,22 @@ func (c *elasticQuotas) Update(ctx context.Context, elasticQuota *v1alpha1.Elast
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *elasticQuotas) UpdateStatus(ctx context.Context, elasticQuota *v1alpha1.ElasticQuota, opts v1.UpdateOptions) (result *v1alpha1.ElasticQuota, err error) {
+	result = &v1alpha1.ElasticQuota{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("elasticquotas").
+		Name(elasticQuota.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(elasticQuota).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the elasticQuota and deletes it. Returns an error if one occurs.
 func (c *elasticQuotas) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
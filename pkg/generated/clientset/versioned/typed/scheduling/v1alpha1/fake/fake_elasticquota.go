@@ -102,6 +102,18 @@ func (c *FakeElasticQuotas) Update(ctx context.Context, elasticQuota *v1alpha1.E
 	return obj.(*v1alpha1.ElasticQuota), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeElasticQuotas) UpdateStatus(ctx context.Context, elasticQuota *v1alpha1.ElasticQuota, opts v1.UpdateOptions) (*v1alpha1.ElasticQuota, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(elasticquotasResource, "status", c.ns, elasticQuota), &v1alpha1.ElasticQuota{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ElasticQuota), err
+}
+
 // Delete takes name of the elasticQuota and deletes it. Returns an error if one occurs.
 func (c *FakeElasticQuotas) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -21,6 +21,13 @@ import "fmt"
 const (
 	// PodGroupLabel is the default label of coscheduling
 	PodGroupLabel = "pod-group.scheduling.sigs.k8s.io"
+	// RoleLabel is the default label of the role of a pod in its PodGroup
+	RoleLabel = "role.scheduling.sigs.k8s.io"
+	// PodGroupMinAvailableLabel is the label or annotation of the minimum number of members of a PodGroup
+	// declared without the PodGroup CRD
+	PodGroupMinAvailableLabel = "pod-group.scheduling.sigs.k8s.io/min-available"
+	// BackfillAnnotation marks a pod that may be backfilled onto nodes reserved for PodGroups if set to "true"
+	BackfillAnnotation = "backfill.scheduling.sigs.k8s.io"
 )
 
 var (
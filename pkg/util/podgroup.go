@@ -19,12 +19,21 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
 )
 
 // DefaultWaitTime is 60s if ScheduleTimeoutSeconds is not specified.
@@ -47,18 +56,44 @@ func CreateMergePatch(original, new interface{}) ([]byte, error) {
 	return patch, nil
 }
 
-// GetPodGroupLabel get pod group from pod annotations
-func GetPodGroupLabel(pod *v1.Pod) string {
-	return pod.Labels[PodGroupLabel]
+// GetPodGroupMinAvailable returns the minimum number of members of the PodGroup declared by the labels
+// or annotations of the pod, and false if it is not declared or invalid.
+func GetPodGroupMinAvailable(pod *v1.Pod) (int32, bool) {
+	value, ok := pod.Labels[PodGroupMinAvailableLabel]
+	if !ok {
+		if value, ok = pod.Annotations[PodGroupMinAvailableLabel]; !ok {
+			return 0, false
+		}
+	}
+	minAvailable, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || minAvailable < 1 {
+		klog.V(5).Infof("Invalid %v %q of pod %v/%v", PodGroupMinAvailableLabel, value, pod.Namespace, pod.Name)
+		return 0, false
+	}
+	return int32(minAvailable), true
 }
 
-// GetPodGroupFullName get namespaced group name from pod annotations
-func GetPodGroupFullName(pod *v1.Pod) string {
-	pgName := GetPodGroupLabel(pod)
-	if len(pgName) == 0 {
-		return ""
+// GetOccupiedBy returns the owners of a pod in the form of status.occupiedBy of PodGroups: the sorted
+// namespace/name of every top-level owner, joined by commas. It is empty if the pod has no owner.
+// ReplicaSets of Deployments resolve to the Deployments, so that the pods of every revision of a
+// Deployment are owned by the same workload.
+func GetOccupiedBy(pod *v1.Pod) string {
+	var refs []string
+	for _, ownerRef := range pod.OwnerReferences {
+		refs = append(refs, fmt.Sprintf("%s/%s", pod.Namespace, getTopLevelOwnerName(pod, ownerRef)))
+	}
+	sort.Strings(refs)
+	return strings.Join(refs, ",")
+}
+
+// getTopLevelOwnerName returns the name of the Deployment of a pod owned by one of its ReplicaSets, which are
+// named after the Deployment and the pod-template-hash label of their pods, or the name of the owner otherwise.
+func getTopLevelOwnerName(pod *v1.Pod, ownerRef metav1.OwnerReference) string {
+	hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	if !ok || ownerRef.Kind != "ReplicaSet" || !strings.HasSuffix(ownerRef.Name, "-"+hash) {
+		return ownerRef.Name
 	}
-	return fmt.Sprintf("%v/%v", pod.Namespace, pgName)
+	return strings.TrimSuffix(ownerRef.Name, "-"+hash)
 }
 
 // GetWaitTimeDuration returns a wait timeout based on the following precedences:
@@ -74,3 +109,164 @@ func GetWaitTimeDuration(pg *v1alpha1.PodGroup, scheduleTimeout *time.Duration)
 	}
 	return DefaultWaitTime
 }
+
+// GetPodRole returns the name of the first role in spec.roles of the given pg that selects the pod.
+// An empty string is returned if no role selects the pod.
+func GetPodRole(pg *v1alpha1.PodGroup, pod *v1.Pod) string {
+	for _, role := range pg.Spec.Roles {
+		selector := labels.SelectorFromSet(labels.Set{RoleLabel: role.Name})
+		if role.Selector != nil {
+			var err error
+			selector, err = metav1.LabelSelectorAsSelector(role.Selector)
+			if err != nil {
+				klog.Errorf("Invalid selector of role %v in PodGroup %v/%v: %v", role.Name, pg.Namespace, pg.Name, err)
+				continue
+			}
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return role.Name
+		}
+	}
+	return ""
+}
+
+// CountPodsByRole returns the number of the given pods of each role in spec.roles of the given pg.
+func CountPodsByRole(pg *v1alpha1.PodGroup, pods []*v1.Pod) map[string]int32 {
+	counts := make(map[string]int32, len(pg.Spec.Roles))
+	for _, pod := range pods {
+		if role := GetPodRole(pg, pod); len(role) != 0 {
+			counts[role]++
+		}
+	}
+	return counts
+}
+
+// GetUnsatisfiedRole returns the name of the first role in spec.roles of the given pg whose count
+// is less than its minMember. An empty string is returned if the minimum of every role is met.
+func GetUnsatisfiedRole(pg *v1alpha1.PodGroup, counts map[string]int32) string {
+	for _, role := range pg.Spec.Roles {
+		if counts[role.Name] < role.MinMember {
+			return role.Name
+		}
+	}
+	return ""
+}
+
+// GetPodGroupCondition returns the condition of the given type in the status of a pg, or nil if it is not set.
+func GetPodGroupCondition(status *v1alpha1.PodGroupStatus, conditionType v1alpha1.PodGroupConditionType) *v1alpha1.PodGroupCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetPodGroupCondition sets a condition in the status of a pg. LastTransitionTime is only updated, and true is
+// returned, if the status or the reason of the condition changes; otherwise only the message is updated.
+func SetPodGroupCondition(status *v1alpha1.PodGroupStatus, condition v1alpha1.PodGroupCondition) bool {
+	existing := GetPodGroupCondition(status, condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		status.Conditions = append(status.Conditions, condition)
+		return true
+	}
+	if existing.Status == condition.Status && existing.Reason == condition.Reason {
+		existing.Message = condition.Message
+		return false
+	}
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*existing = condition
+	return true
+}
+
+// phaseOrder orders the phases that satisfy the dependencies of PodGroups.
+var phaseOrder = map[v1alpha1.PodGroupPhase]int{
+	v1alpha1.PodGroupScheduled: 1,
+	v1alpha1.PodGroupRunning:   2,
+	v1alpha1.PodGroupFinished:  3,
+}
+
+// IsPhaseReached returns whether a pg in the given phase satisfies a dependency on the required phase.
+// An empty required phase means Running.
+func IsPhaseReached(phase, required v1alpha1.PodGroupPhase) bool {
+	if len(required) == 0 {
+		required = v1alpha1.PodGroupRunning
+	}
+	return phaseOrder[phase] != 0 && phaseOrder[phase] >= phaseOrder[required]
+}
+
+// GetUnsatisfiedDependency returns a message describing the first pg in spec.dependsOn of the given pg that
+// has not reached its required phase. An empty string is returned if every dependency is satisfied.
+func GetUnsatisfiedDependency(pg *v1alpha1.PodGroup, pgLister pglister.PodGroupLister) string {
+	for _, dependency := range pg.Spec.DependsOn {
+		required := dependency.Phase
+		if len(required) == 0 {
+			required = v1alpha1.PodGroupRunning
+		}
+		dependencyPG, err := pgLister.PodGroups(pg.Namespace).Get(dependency.Name)
+		if err != nil {
+			return fmt.Sprintf("waiting for pod group %v to be %v: %v", dependency.Name, required, err)
+		}
+		if !IsPhaseReached(dependencyPG.Status.Phase, required) {
+			return fmt.Sprintf("waiting for pod group %v to be %v, current phase: %q", dependency.Name, required, dependencyPG.Status.Phase)
+		}
+	}
+	return ""
+}
+
+// FindDependencyCycle returns the names of the pgs on a cycle of spec.dependsOn that goes through the given pg,
+// starting and ending with its name, or nil if there is none. The other pgs are looked up with pgLister, so that
+// a new version of the given pg can be checked before it is stored.
+func FindDependencyCycle(pg *v1alpha1.PodGroup, pgLister pglister.PodGroupLister) []string {
+	visited := sets.NewString(pg.Name)
+	var path []string
+	var visit func(current *v1alpha1.PodGroup) bool
+	visit = func(current *v1alpha1.PodGroup) bool {
+		path = append(path, current.Name)
+		for _, dependency := range current.Spec.DependsOn {
+			if dependency.Name == pg.Name {
+				path = append(path, pg.Name)
+				return true
+			}
+			if visited.Has(dependency.Name) {
+				continue
+			}
+			visited.Insert(dependency.Name)
+			next, err := pgLister.PodGroups(pg.Namespace).Get(dependency.Name)
+			if err == nil && visit(next) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(pg) {
+		return path
+	}
+	return nil
+}
+
+// GetDependencyCycle returns a message describing the cycle of spec.dependsOn that goes through the given pg,
+// or an empty string if there is none.
+func GetDependencyCycle(pg *v1alpha1.PodGroup, pgLister pglister.PodGroupLister) string {
+	cycle := FindDependencyCycle(pg, pgLister)
+	if len(cycle) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("pod groups depend on each other: %v", strings.Join(cycle, " -> "))
+}
+
+// DependsOn returns whether spec.dependsOn of the given pg contains the pg of the given name.
+func DependsOn(pg *v1alpha1.PodGroup, name string) bool {
+	for _, dependency := range pg.Spec.DependsOn {
+		if dependency.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/config"
+)
+
+// PodGroupKeyType is the type of a PodGroup key.
+type PodGroupKeyType string
+
+const (
+	// PodGroupKeyLabel is the type of label keys.
+	PodGroupKeyLabel PodGroupKeyType = "Label"
+	// PodGroupKeyAnnotation is the type of annotation keys.
+	PodGroupKeyAnnotation PodGroupKeyType = "Annotation"
+)
+
+// PodGroupKey is a label or annotation key that holds the name of the PodGroup of a pod.
+// It has the same representation as the podGroupKeys in the args of the Coscheduling plugin.
+type PodGroupKey struct {
+	// Type is the type of the key, either Label or Annotation. It defaults to Label.
+	Type PodGroupKeyType `json:"type,omitempty"`
+	// Key is the label or annotation key.
+	Key string `json:"key"`
+}
+
+// PodGroupResolver resolves the PodGroup of a pod from an ordered list of label and annotation keys.
+// The first key that is set on the pod is used.
+type PodGroupResolver struct {
+	keys []PodGroupKey
+}
+
+// DefaultPodGroupResolver resolves the PodGroup of a pod from the PodGroupLabel label.
+var DefaultPodGroupResolver = NewPodGroupResolver(nil)
+
+// NewPodGroupResolver creates a PodGroupResolver with the podGroupKeys in the args of the plugins,
+// or with the PodGroupLabel label if no keys are given.
+func NewPodGroupResolver(keys []config.PodGroupKey) *PodGroupResolver {
+	var pgKeys []PodGroupKey
+	for _, key := range keys {
+		pgKeys = append(pgKeys, PodGroupKey{Type: PodGroupKeyType(key.Type), Key: key.Key})
+	}
+	if len(pgKeys) == 0 {
+		pgKeys = []PodGroupKey{{Type: PodGroupKeyLabel, Key: PodGroupLabel}}
+	}
+	return &PodGroupResolver{keys: pgKeys}
+}
+
+// ParsePodGroupKeys parses a YAML or JSON list of keys in the same form as the podGroupKeys in the args of
+// the Coscheduling plugin, e.g. `[{type: Annotation, key: scheduling.k8s.io/group-name}]`. Keys without
+// a type are label keys. No keys are returned for an empty value.
+func ParsePodGroupKeys(value string) ([]config.PodGroupKey, error) {
+	var pgKeys []PodGroupKey
+	if err := yaml.UnmarshalStrict([]byte(value), &pgKeys); err != nil {
+		return nil, fmt.Errorf("invalid PodGroup keys %q: %v", value, err)
+	}
+	var keys []config.PodGroupKey
+	for _, key := range pgKeys {
+		if len(key.Type) == 0 {
+			key.Type = PodGroupKeyLabel
+		}
+		if key.Type != PodGroupKeyLabel && key.Type != PodGroupKeyAnnotation {
+			return nil, fmt.Errorf("invalid type %q of PodGroup key %q", key.Type, key.Key)
+		}
+		if len(key.Key) == 0 {
+			return nil, fmt.Errorf("empty PodGroup key of type %q", key.Type)
+		}
+		keys = append(keys, config.PodGroupKey{Type: config.PodGroupKeyType(key.Type), Key: key.Key})
+	}
+	return keys, nil
+}
+
+// GetPodGroupName returns the name of the PodGroup of the pod, or an empty string if the pod does not belong to any.
+func (r *PodGroupResolver) GetPodGroupName(pod *v1.Pod) string {
+	for _, key := range r.keys {
+		values := pod.Labels
+		if key.Type == PodGroupKeyAnnotation {
+			values = pod.Annotations
+		}
+		if name := values[key.Key]; len(name) != 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// PrimaryKey returns the first key, which is set on the pods of the PodGroups created for workloads.
+func (r *PodGroupResolver) PrimaryKey() PodGroupKey {
+	return r.keys[0]
+}
+
+// GetPodGroupFullName returns the namespaced name of the PodGroup of the pod,
+// or an empty string if the pod does not belong to any.
+func (r *PodGroupResolver) GetPodGroupFullName(pod *v1.Pod) string {
+	pgName := r.GetPodGroupName(pod)
+	if len(pgName) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v/%v", pod.Namespace, pgName)
+}
+
+// IsMember returns whether the pod belongs to the PodGroup with the given namespace and name.
+func (r *PodGroupResolver) IsMember(pod *v1.Pod, namespace, pgName string) bool {
+	return pod.Namespace == namespace && r.GetPodGroupName(pod) == pgName
+}
+
+// LabelSelector returns the label selector for pod informers to watch pods that may belong to PodGroups.
+// All pods are selected if any of the keys is an annotation key or there are several keys,
+// as label selectors cannot select on annotations or on any of several labels.
+func (r *PodGroupResolver) LabelSelector() string {
+	if len(r.keys) == 1 && r.keys[0].Type != PodGroupKeyAnnotation {
+		return r.keys[0].Key
+	}
+	return ""
+}
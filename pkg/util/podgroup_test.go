@@ -17,9 +17,16 @@ limitations under the License.
 package util
 
 import (
+	"strings"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/apis/core"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	fakepgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pgformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
 )
 
 type test struct {
@@ -76,3 +83,246 @@ func TestCreateMergePatch(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPodGroupMinAvailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    int32
+		expectedOK  bool
+	}{
+		{
+			name: "not declared",
+		},
+		{
+			name:       "declared by label",
+			labels:     map[string]string{PodGroupMinAvailableLabel: "3"},
+			expected:   3,
+			expectedOK: true,
+		},
+		{
+			name:        "declared by annotation",
+			annotations: map[string]string{PodGroupMinAvailableLabel: "2"},
+			expected:    2,
+			expectedOK:  true,
+		},
+		{
+			name:   "invalid value",
+			labels: map[string]string{PodGroupMinAvailableLabel: "two"},
+		},
+		{
+			name:   "non-positive value",
+			labels: map[string]string{PodGroupMinAvailableLabel: "0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: tt.labels, Annotations: tt.annotations}}
+			got, ok := GetPodGroupMinAvailable(pod)
+			if got != tt.expected || ok != tt.expectedOK {
+				t.Errorf("expected %v, %v, got %v, %v", tt.expected, tt.expectedOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestGetOccupiedBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		owners   []metav1.OwnerReference
+		expected string
+	}{
+		{
+			name: "no owner",
+		},
+		{
+			name:     "owners sorted",
+			owners:   []metav1.OwnerReference{{Kind: "MPIJob", Name: "mpijob-a"}, {Kind: "Job", Name: "job-a"}},
+			expected: "ns/job-a,ns/mpijob-a",
+		},
+		{
+			name:     "replicaset of a deployment",
+			labels:   map[string]string{"pod-template-hash": "5d4f8b7c9"},
+			owners:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8b7c9"}},
+			expected: "ns/web",
+		},
+		{
+			name:     "replicaset without deployment",
+			owners:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8b7c9"}},
+			expected: "ns/web-5d4f8b7c9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns", Labels: tt.labels,
+				OwnerReferences: tt.owners}}
+			if got := GetOccupiedBy(pod); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetPodGroupCondition(t *testing.T) {
+	status := &v1alpha1.PodGroupStatus{}
+	condition := v1alpha1.PodGroupCondition{
+		Type:    v1alpha1.PodGroupScheduledCondition,
+		Status:  v1.ConditionFalse,
+		Reason:  v1alpha1.PodGroupNotEnoughMembersReason,
+		Message: "1 of 3",
+	}
+	if !SetPodGroupCondition(status, condition) {
+		t.Errorf("expected a new condition to be a transition")
+	}
+	transitionTime := status.Conditions[0].LastTransitionTime
+	if transitionTime.IsZero() {
+		t.Errorf("expected lastTransitionTime to be set")
+	}
+
+	condition.Message = "2 of 3"
+	if SetPodGroupCondition(status, condition) {
+		t.Errorf("expected a message change not to be a transition")
+	}
+	if got := GetPodGroupCondition(status, v1alpha1.PodGroupScheduledCondition); got.Message != "2 of 3" ||
+		!got.LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("expected only the message to be updated, got %+v", got)
+	}
+
+	condition.Reason = v1alpha1.PodGroupInsufficientResourcesReason
+	if !SetPodGroupCondition(status, condition) {
+		t.Errorf("expected a reason change to be a transition")
+	}
+	if len(status.Conditions) != 1 {
+		t.Errorf("expected 1 condition, got %v", len(status.Conditions))
+	}
+	if GetPodGroupCondition(status, v1alpha1.PodGroupBackingOffCondition) != nil {
+		t.Errorf("expected no BackingOff condition")
+	}
+}
+
+func TestIsPhaseReached(t *testing.T) {
+	for _, tt := range []struct {
+		phase    v1alpha1.PodGroupPhase
+		required v1alpha1.PodGroupPhase
+		expected bool
+	}{
+		{phase: v1alpha1.PodGroupPending, required: v1alpha1.PodGroupScheduled, expected: false},
+		{phase: v1alpha1.PodGroupScheduled, required: v1alpha1.PodGroupScheduled, expected: true},
+		{phase: v1alpha1.PodGroupFinished, required: v1alpha1.PodGroupScheduled, expected: true},
+		{phase: v1alpha1.PodGroupScheduled, required: "", expected: false},
+		{phase: v1alpha1.PodGroupRunning, required: "", expected: true},
+		{phase: v1alpha1.PodGroupFailed, required: v1alpha1.PodGroupScheduled, expected: false},
+		{phase: v1alpha1.PodGroupRunning, required: v1alpha1.PodGroupFinished, expected: false},
+	} {
+		if got := IsPhaseReached(tt.phase, tt.required); got != tt.expected {
+			t.Errorf("expected phase %q to reach %q: %v, got %v", tt.phase, tt.required, tt.expected, got)
+		}
+	}
+}
+
+func TestFindDependencyCycle(t *testing.T) {
+	makePG := func(name string, dependencies ...string) *v1alpha1.PodGroup {
+		pg := &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"}}
+		for _, dependency := range dependencies {
+			pg.Spec.DependsOn = append(pg.Spec.DependsOn, v1alpha1.PodGroupDependency{Name: dependency})
+		}
+		return pg
+	}
+	pgInformer := pgformers.NewSharedInformerFactory(fakepgclientset.NewSimpleClientset(), 0).Scheduling().V1alpha1().PodGroups()
+	for _, pg := range []*v1alpha1.PodGroup{makePG("a", "b"), makePG("b", "c"), makePG("c"), makePG("d", "a")} {
+		pgInformer.Informer().GetStore().Add(pg)
+	}
+	tests := []struct {
+		name     string
+		pg       *v1alpha1.PodGroup
+		expected []string
+	}{
+		{
+			name: "no cycle",
+			pg:   makePG("a", "b"),
+		},
+		{
+			name:     "self dependency",
+			pg:       makePG("c", "c"),
+			expected: []string{"c", "c"},
+		},
+		{
+			name:     "cycle through other pod groups",
+			pg:       makePG("c", "d"),
+			expected: []string{"c", "d", "a", "b", "c"},
+		},
+		{
+			name: "missing dependencies are skipped",
+			pg:   makePG("e", "a", "missing"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindDependencyCycle(tt.pg, pgInformer.Lister())
+			if len(got) != len(tt.expected) || strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPodGroupResolver(t *testing.T) {
+	keys, err := ParsePodGroupKeys(`[{type: Annotation, key: scheduling.k8s.io/group-name}, {type: Label, key: group}, {key: ` +
+		PodGroupLabel + `}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewPodGroupResolver(keys)
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name: "no key is set",
+		},
+		{
+			name:     "default label",
+			labels:   map[string]string{PodGroupLabel: "pg1"},
+			expected: "pg1",
+		},
+		{
+			name:        "earlier keys take precedence",
+			labels:      map[string]string{PodGroupLabel: "pg1", "group": "pg2"},
+			annotations: map[string]string{"scheduling.k8s.io/group-name": "pg3"},
+			expected:    "pg3",
+		},
+		{
+			name:        "annotation keys are not looked up in labels",
+			labels:      map[string]string{"scheduling.k8s.io/group-name": "pg3", "group": "pg2"},
+			annotations: map[string]string{"group": "pg1"},
+			expected:    "pg2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns1", Labels: tt.labels, Annotations: tt.annotations}}
+			if got := resolver.GetPodGroupName(pod); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if selector := resolver.LabelSelector(); selector != "" {
+		t.Errorf("expected all pods to be selected, got selector %q", selector)
+	}
+	if selector := DefaultPodGroupResolver.LabelSelector(); selector != PodGroupLabel {
+		t.Errorf("expected selector %q, got %q", PodGroupLabel, selector)
+	}
+	for _, value := range []string{`[{type: Annotation}]`, `[{type: Env, key: group}]`, `[{name: group}]`} {
+		if _, err := ParsePodGroupKeys(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+	if keys, err := ParsePodGroupKeys(""); err != nil || len(keys) != 0 {
+		t.Errorf("expected no keys for an empty value, got %v, %v", keys, err)
+	}
+}
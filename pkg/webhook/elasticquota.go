@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// mutateElasticQuota sets the defaults of an ElasticQuota.
+func (wh *Webhook) mutateElasticQuota(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	eq := &v1alpha1.ElasticQuota{}
+	if err := json.Unmarshal(req.Object.Raw, eq); err != nil {
+		return errored(err)
+	}
+	spec := eq.Spec.DeepCopy()
+	SetElasticQuotaDefaults(spec)
+	if equality.Semantic.DeepEqual(spec, &eq.Spec) {
+		return allowed()
+	}
+	return patched("/spec", spec)
+}
+
+// validateElasticQuota rejects an ElasticQuota with an invalid spec, or a new ElasticQuota in a namespace
+// that already has one.
+func (wh *Webhook) validateElasticQuota(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	eq := &v1alpha1.ElasticQuota{}
+	if err := json.Unmarshal(req.Object.Raw, eq); err != nil {
+		return errored(err)
+	}
+	if errs := ValidateElasticQuota(eq); len(errs) != 0 {
+		return invalid(errs)
+	}
+	if req.Operation != admissionv1.Create {
+		return allowed()
+	}
+
+	// The capacity scheduling plugin only accounts for one ElasticQuota per namespace. The ElasticQuotas are
+	// listed from the apiserver rather than a cache, so that one that has just been created is not missed.
+	eqs, err := wh.pgClient.SchedulingV1alpha1().ElasticQuotas(req.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return errored(err)
+	}
+	for _, existing := range eqs.Items {
+		if existing.Name != req.Name {
+			return invalid(field.ErrorList{field.Forbidden(field.NewPath("metadata", "namespace"),
+				fmt.Sprintf("namespace %v already has ElasticQuota %v", req.Namespace, existing.Name))})
+		}
+	}
+	return allowed()
+}
+
+// SetElasticQuotaDefaults sets the defaults of the spec of an ElasticQuota:
+// 1. the max of a resource that only has a min defaults to the min, as a max that is not set is zero.
+// 2. the min of a resource that only has a max defaults to zero.
+func SetElasticQuotaDefaults(spec *v1alpha1.ElasticQuotaSpec) {
+	for name, min := range spec.Min {
+		if _, ok := spec.Max[name]; !ok {
+			if spec.Max == nil {
+				spec.Max = v1.ResourceList{}
+			}
+			spec.Max[name] = min.DeepCopy()
+		}
+	}
+	for name, max := range spec.Max {
+		if _, ok := spec.Min[name]; !ok {
+			if spec.Min == nil {
+				spec.Min = v1.ResourceList{}
+			}
+			spec.Min[name] = *resource.NewQuantity(0, max.Format)
+		}
+	}
+}
+
+// ValidateElasticQuota validates the spec of an ElasticQuota.
+func ValidateElasticQuota(eq *v1alpha1.ElasticQuota) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, validateResourceList(eq.Spec.Min, specPath.Child("min"))...)
+	allErrs = append(allErrs, validateResourceList(eq.Spec.Max, specPath.Child("max"))...)
+	for name, min := range eq.Spec.Min {
+		max := eq.Spec.Max[name]
+		if min.Cmp(max) > 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("min").Key(string(name)), min.String(),
+				fmt.Sprintf("must be less than or equal to max %v", max.String())))
+		}
+	}
+	return allErrs
+}
+
+// validateResourceList validates that the quantities of a resource list are not negative.
+func validateResourceList(resources v1.ResourceList, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for name, quantity := range resources {
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(string(name)), quantity.String(),
+				"must be greater than or equal to 0"))
+		}
+	}
+	return allErrs
+}
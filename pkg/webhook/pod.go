@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// validatePod rejects a new pod that references a PodGroup that does not exist, as the pod would never be
+// scheduled. Pods that declare the minimum number of members of a PodGroup without the PodGroup CRD are allowed.
+func (wh *Webhook) validatePod(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create {
+		return allowed()
+	}
+	pod := &v1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return errored(err)
+	}
+	pgName := wh.resolver.GetPodGroupName(pod)
+	if len(pgName) == 0 {
+		return allowed()
+	}
+	if _, ok := util.GetPodGroupMinAvailable(pod); ok {
+		return allowed()
+	}
+
+	// The PodGroup may have been created just before the pod, e.g., by the workload controller,
+	// so the apiserver is checked if the PodGroup is not in the cache yet.
+	if _, err := wh.pgLister.PodGroups(req.Namespace).Get(pgName); err == nil {
+		return allowed()
+	} else if !apierrs.IsNotFound(err) {
+		return errored(err)
+	}
+	_, err := wh.pgClient.SchedulingV1alpha1().PodGroups(req.Namespace).Get(context.TODO(), pgName, metav1.GetOptions{})
+	if err == nil {
+		return allowed()
+	}
+	if apierrs.IsNotFound(err) {
+		return denied(http.StatusForbidden, metav1.StatusReasonForbidden,
+			fmt.Sprintf("PodGroup %v/%v of the pod does not exist", req.Namespace, pgName))
+	}
+	return errored(err)
+}
+
+// mutatePod sets the PodGroup of a new pod whose controller owns a PodGroup of the same name, such as the pods of
+// the workloads that the workload controller creates PodGroups for, so that their pod templates are left unchanged.
+// Pods that already declare a PodGroup are left unchanged.
+func (wh *Webhook) mutatePod(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create {
+		return allowed()
+	}
+	pod := &v1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return errored(err)
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || len(wh.resolver.GetPodGroupName(pod)) != 0 {
+		return allowed()
+	}
+	pg, err := wh.pgLister.PodGroups(req.Namespace).Get(owner.Name)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			return allowed()
+		}
+		return errored(err)
+	}
+	if pgOwner := metav1.GetControllerOf(pg); pgOwner == nil || pgOwner.UID != owner.UID {
+		return allowed()
+	}
+
+	key := wh.resolver.PrimaryKey()
+	path, values := "/metadata/labels", pod.Labels
+	if key.Type == util.PodGroupKeyAnnotation {
+		path, values = "/metadata/annotations", pod.Annotations
+	}
+	result := map[string]string{key.Key: pg.Name}
+	for k, v := range values {
+		result[k] = v
+	}
+	return patched(path, result)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	schedlister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+var (
+	validBindFailurePolicies = sets.NewString(string(v1alpha1.BindFailureRequeue), string(v1alpha1.BindFailureRollback))
+	validFailurePolicies     = sets.NewString(string(v1alpha1.FailureIgnore), string(v1alpha1.FailureFailGroup),
+		string(v1alpha1.FailureRestartGroup))
+	validDependencyPhases = sets.NewString(string(v1alpha1.PodGroupScheduled), string(v1alpha1.PodGroupRunning),
+		string(v1alpha1.PodGroupFinished))
+)
+
+// mutatePodGroup sets the defaults of a PodGroup.
+func (wh *Webhook) mutatePodGroup(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	pg := &v1alpha1.PodGroup{}
+	if err := json.Unmarshal(req.Object.Raw, pg); err != nil {
+		return errored(err)
+	}
+	spec := pg.Spec.DeepCopy()
+	SetPodGroupDefaults(spec)
+	if equality.Semantic.DeepEqual(spec, &pg.Spec) {
+		return allowed()
+	}
+	return patched("/spec", spec)
+}
+
+// validatePodGroup rejects a PodGroup with an invalid spec, or that closes a cycle of dependencies
+// with the cached PodGroups.
+func (wh *Webhook) validatePodGroup(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	pg := &v1alpha1.PodGroup{}
+	if err := json.Unmarshal(req.Object.Raw, pg); err != nil {
+		return errored(err)
+	}
+	if len(pg.Namespace) == 0 {
+		pg.Namespace = req.Namespace
+	}
+	pgLister := wh.pgLister
+	if req.Operation == admissionv1.Update {
+		old := &v1alpha1.PodGroup{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return errored(err)
+		}
+		// Updates that leave the spec unchanged, e.g. of the metadata, are not validated again.
+		if equality.Semantic.DeepEqual(old.Spec, pg.Spec) {
+			return allowed()
+		}
+		// Only cycles introduced by the update of spec.dependsOn are reported.
+		if equality.Semantic.DeepEqual(old.Spec.DependsOn, pg.Spec.DependsOn) {
+			pgLister = nil
+		}
+	}
+	if errs := ValidatePodGroup(pg, pgLister); len(errs) != 0 {
+		return invalid(errs)
+	}
+	return allowed()
+}
+
+// SetPodGroupDefaults sets the defaults of the spec of a PodGroup:
+// 1. minMember defaults to 1, as a PodGroup needs at least one member to run.
+// 2. the phase of a dependency defaults to Running.
+func SetPodGroupDefaults(spec *v1alpha1.PodGroupSpec) {
+	if spec.MinMember == 0 {
+		spec.MinMember = 1
+	}
+	for i := range spec.DependsOn {
+		if len(spec.DependsOn[i].Phase) == 0 {
+			spec.DependsOn[i].Phase = v1alpha1.PodGroupRunning
+		}
+	}
+}
+
+// ValidatePodGroup validates the spec of a PodGroup. Cycles of spec.dependsOn through other PodGroups
+// are looked up with pgLister, unless it is nil.
+func ValidatePodGroup(pg *v1alpha1.PodGroup, pgLister schedlister.PodGroupLister) field.ErrorList {
+	var allErrs field.ErrorList
+	spec := pg.Spec
+	specPath := field.NewPath("spec")
+
+	if spec.MinMember < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minMember"), spec.MinMember, "must be greater than 0"))
+	}
+	if spec.MaxMember != nil && *spec.MaxMember < spec.MinMember {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxMember"), *spec.MaxMember,
+			"must be greater than or equal to minMember"))
+	}
+	if spec.MinResources != nil {
+		allErrs = append(allErrs, validateResourceList(*spec.MinResources, specPath.Child("minResources"))...)
+	}
+	allErrs = append(allErrs, validateNonNegative(spec.ScheduleTimeoutSeconds, specPath.Child("scheduleTimeoutSeconds"))...)
+	allErrs = append(allErrs, validateNonNegative(spec.TTLSecondsAfterFinished, specPath.Child("ttlSecondsAfterFinished"))...)
+	allErrs = append(allErrs, validateNonNegative(&spec.MaxRestarts, specPath.Child("maxRestarts"))...)
+
+	if tc := spec.TopologyConstraint; tc != nil {
+		tcPath := specPath.Child("topologyConstraint")
+		if len(tc.RequiredTopologyKey) != 0 {
+			allErrs = append(allErrs, validateLabelKey(tc.RequiredTopologyKey, tcPath.Child("requiredTopologyKey"))...)
+		}
+		for i, key := range tc.PreferredTopologyKeys {
+			allErrs = append(allErrs, validateLabelKey(key, tcPath.Child("preferredTopologyKeys").Index(i))...)
+		}
+	}
+
+	roleNames := sets.NewString()
+	for i, role := range spec.Roles {
+		rolePath := specPath.Child("roles").Index(i)
+		if len(role.Name) == 0 {
+			allErrs = append(allErrs, field.Required(rolePath.Child("name"), ""))
+		} else if roleNames.Has(role.Name) {
+			allErrs = append(allErrs, field.Duplicate(rolePath.Child("name"), role.Name))
+		}
+		roleNames.Insert(role.Name)
+		if role.Selector != nil {
+			allErrs = append(allErrs, metav1validation.ValidateLabelSelector(role.Selector, rolePath.Child("selector"))...)
+		}
+		allErrs = append(allErrs, validateNonNegative(&role.MinMember, rolePath.Child("minMember"))...)
+	}
+
+	if len(spec.BindFailurePolicy) != 0 && !validBindFailurePolicies.Has(string(spec.BindFailurePolicy)) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("bindFailurePolicy"), spec.BindFailurePolicy,
+			validBindFailurePolicies.List()))
+	}
+	if len(spec.FailurePolicy) != 0 && !validFailurePolicies.Has(string(spec.FailurePolicy)) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("failurePolicy"), spec.FailurePolicy,
+			validFailurePolicies.List()))
+	}
+
+	for i, dependency := range spec.DependsOn {
+		dependencyPath := specPath.Child("dependsOn").Index(i)
+		if len(dependency.Name) == 0 {
+			allErrs = append(allErrs, field.Required(dependencyPath.Child("name"), ""))
+		} else if dependency.Name == pg.Name {
+			allErrs = append(allErrs, field.Invalid(dependencyPath.Child("name"), dependency.Name,
+				"a pod group cannot depend on itself"))
+		}
+		if len(dependency.Phase) != 0 && !validDependencyPhases.Has(string(dependency.Phase)) {
+			allErrs = append(allErrs, field.NotSupported(dependencyPath.Child("phase"), dependency.Phase,
+				validDependencyPhases.List()))
+		}
+	}
+	// A self-dependency is reported above, and would be found as a cycle as well.
+	if pgLister != nil && !util.DependsOn(pg, pg.Name) {
+		if message := util.GetDependencyCycle(pg, pgLister); len(message) != 0 {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("dependsOn"), message))
+		}
+	}
+	return allErrs
+}
+
+// validateNonNegative validates that an optional integer is not negative.
+func validateNonNegative(value *int32, fldPath *field.Path) field.ErrorList {
+	if value != nil && *value < 0 {
+		return field.ErrorList{field.Invalid(fldPath, *value, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+// validateLabelKey validates that a topology key is a valid node label key.
+func validateLabelKey(key string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(key) {
+		allErrs = append(allErrs, field.Invalid(fldPath, key, msg))
+	}
+	return allErrs
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	pgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions/scheduling/v1alpha1"
+	schedlister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// Paths of the admission webhooks served by Webhook.
+const (
+	MutatePodGroupPath       = "/mutate-podgroup"
+	ValidatePodGroupPath     = "/validate-podgroup"
+	MutateElasticQuotaPath   = "/mutate-elasticquota"
+	ValidateElasticQuotaPath = "/validate-elasticquota"
+	MutatePodPath            = "/mutate-pod"
+	ValidatePodPath          = "/validate-pod"
+)
+
+const (
+	// patchOpAdd adds a field, or replaces it if it exists.
+	patchOpAdd = "add"
+	// maxAdmissionReviewBodySize bounds the size of AdmissionReviews, which hold an object and its old version
+	// that etcd limits to 1.5MB each by default.
+	maxAdmissionReviewBodySize = 4 * 1024 * 1024
+)
+
+// Webhook serves the admission webhooks that default and validate PodGroups and ElasticQuotas,
+// that set the PodGroups of the pods of workloads, and that reject pods of PodGroups that do not exist.
+type Webhook struct {
+	mux            *http.ServeMux
+	pgLister       schedlister.PodGroupLister
+	pgListerSynced cache.InformerSynced
+	pgClient       pgclientset.Interface
+	resolver       *util.PodGroupResolver
+}
+
+// admitFunc admits the object of an admission request, or returns a patch of the object.
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// patchOperation is an operation of a JSON patch.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// NewWebhook returns a new *Webhook
+func NewWebhook(pgInformer schedinformer.PodGroupInformer, pgClient pgclientset.Interface,
+	resolver *util.PodGroupResolver) *Webhook {
+	wh := &Webhook{
+		mux:            http.NewServeMux(),
+		pgLister:       pgInformer.Lister(),
+		pgListerSynced: pgInformer.Informer().HasSynced,
+		pgClient:       pgClient,
+		resolver:       resolver,
+	}
+	wh.handle(MutatePodGroupPath, wh.mutatePodGroup)
+	wh.handle(ValidatePodGroupPath, wh.validatePodGroup)
+	wh.handle(MutateElasticQuotaPath, wh.mutateElasticQuota)
+	wh.handle(ValidateElasticQuotaPath, wh.validateElasticQuota)
+	wh.handle(MutatePodPath, wh.mutatePod)
+	wh.handle(ValidatePodPath, wh.validatePod)
+	return wh
+}
+
+// Run serves the webhooks over TLS at addr once the PodGroup cache is synced, until stopCh is closed.
+func (wh *Webhook) Run(addr, certFile, keyFile string, stopCh <-chan struct{}) error {
+	if !cache.WaitForCacheSync(stopCh, wh.pgListerSynced) {
+		return fmt.Errorf("cannot sync caches")
+	}
+	server := &http.Server{Addr: addr, Handler: wh}
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	klog.Infof("Serving admission webhooks at %v", addr)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wh.mux.ServeHTTP(w, r)
+}
+
+func (wh *Webhook) handle(path string, admit admitFunc) {
+	wh.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, admit)
+	})
+}
+
+// serve decodes an AdmissionReview, and responds with the result of admit.
+func serve(w http.ResponseWriter, r *http.Request, admit admitFunc) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxAdmissionReviewBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("invalid AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := admit(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		klog.Errorf("Error writing admission response: %v", err)
+	}
+}
+
+// allowed returns a response that admits the object as is.
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// denied returns a response that rejects the object with the given reason.
+func denied(code int32, reason metav1.StatusReason, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Result: &metav1.Status{Status: metav1.StatusFailure, Code: code, Reason: reason, Message: message},
+	}
+}
+
+// invalid returns a response that rejects an object that fails validation.
+func invalid(errs field.ErrorList) *admissionv1.AdmissionResponse {
+	return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, errs.ToAggregate().Error())
+}
+
+// errored returns a response that rejects an object that cannot be handled.
+func errored(err error) *admissionv1.AdmissionResponse {
+	return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+}
+
+// patched returns a response that admits the object after replacing the field at path with the given value.
+func patched(path string, value interface{}) *admissionv1.AdmissionResponse {
+	patch, err := json.Marshal([]patchOperation{{Op: patchOpAdd, Path: path, Value: value}})
+	if err != nil {
+		return errored(err)
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}
+}
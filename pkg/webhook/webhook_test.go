@@ -0,0 +1,388 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+func TestWebhook(t *testing.T) {
+	negative := int32(-1)
+	isController := true
+	existingPG := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns1"},
+		Spec:       v1alpha1.PodGroupSpec{MinMember: 2},
+	}
+	uncachedPG := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "uncached", Namespace: "ns1"},
+		Spec:       v1alpha1.PodGroupSpec{MinMember: 2},
+	}
+	jobOwner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "job", Controller: &isController}
+	jobPG := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "ns1", OwnerReferences: []metav1.OwnerReference{jobOwner}},
+		Spec:       v1alpha1.PodGroupSpec{MinMember: 2},
+	}
+	dependentPG := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "dependent", Namespace: "ns1"},
+		Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "pg1"}}},
+	}
+	otherJobOwner := jobOwner
+	otherJobOwner.UID = "other"
+	existingEQ := makeEQ("eq", "ns1", v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+		v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")})
+
+	cases := []struct {
+		name          string
+		path          string
+		operation     admissionv1.Operation
+		namespace     string
+		object        runtime.Object
+		oldObject     runtime.Object
+		expectAllowed bool
+		expectPatch   string
+	}{
+		{
+			name:          "podgroup without defaults",
+			path:          MutatePodGroupPath,
+			object:        existingPG,
+			expectAllowed: true,
+		},
+		{
+			name: "podgroup defaulted",
+			path: MutatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{DependsOn: []v1alpha1.PodGroupDependency{{Name: "pg"}}},
+			},
+			expectAllowed: true,
+			expectPatch:   `[{"op":"add","path":"/spec","value":{"minMember":1,"dependsOn":[{"name":"pg","phase":"Running"}]}}]`,
+		},
+		{
+			name:          "valid podgroup",
+			path:          ValidatePodGroupPath,
+			object:        existingPG,
+			expectAllowed: true,
+		},
+		{
+			name: "podgroup with negative minMember",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: -1},
+			},
+		},
+		{
+			name: "podgroup with maxMember less than minMember",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 2, MaxMember: func() *int32 { m := int32(1); return &m }()},
+			},
+		},
+		{
+			name: "podgroup with negative ttl",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, TTLSecondsAfterFinished: &negative},
+			},
+		},
+		{
+			name: "podgroup depending on itself",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "pg1"}}},
+			},
+		},
+		{
+			name: "podgroup depending on a podgroup that depends on it",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+		},
+		{
+			name:      "podgroup depending on a podgroup in another namespace with the same name",
+			path:      ValidatePodGroupPath,
+			namespace: "ns2",
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns2"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			expectAllowed: true,
+		},
+		{
+			name:      "podgroup in a cycle updated without changing the spec",
+			path:      ValidatePodGroupPath,
+			operation: admissionv1.Update,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1", Labels: map[string]string{"app": "pg1"}},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			oldObject: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			expectAllowed: true,
+		},
+		{
+			name:      "podgroup in a cycle updated without changing dependsOn",
+			path:      ValidatePodGroupPath,
+			operation: admissionv1.Update,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 2, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			oldObject: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			expectAllowed: true,
+		},
+		{
+			name:      "podgroup updated to depend on a podgroup that depends on it",
+			path:      ValidatePodGroupPath,
+			operation: admissionv1.Update,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, DependsOn: []v1alpha1.PodGroupDependency{{Name: "dependent"}}},
+			},
+			oldObject: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1},
+			},
+		},
+		{
+			name:      "podgroup updated with negative minMember",
+			path:      ValidatePodGroupPath,
+			operation: admissionv1.Update,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: -1},
+			},
+			oldObject: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1},
+			},
+		},
+		{
+			name: "podgroup with duplicate roles",
+			path: ValidatePodGroupPath,
+			object: &v1alpha1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"},
+				Spec:       v1alpha1.PodGroupSpec{MinMember: 1, Roles: []v1alpha1.PodGroupRole{{Name: "worker"}, {Name: "worker"}}},
+			},
+		},
+		{
+			name:          "elasticquota defaulted",
+			path:          MutateElasticQuotaPath,
+			object:        makeEQ("eq2", "ns2", v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}, v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}),
+			expectAllowed: true,
+			expectPatch:   `[{"op":"add","path":"/spec","value":{"min":{"cpu":"1","memory":"0"},"max":{"cpu":"1","memory":"1Gi"}}}]`,
+		},
+		{
+			name:          "valid elasticquota",
+			path:          ValidateElasticQuotaPath,
+			namespace:     "ns2",
+			object:        makeEQ("eq2", "ns2", v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}, v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}),
+			expectAllowed: true,
+		},
+		{
+			name:      "elasticquota with min greater than max",
+			path:      ValidateElasticQuotaPath,
+			namespace: "ns2",
+			object:    makeEQ("eq2", "ns2", v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}, v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}),
+		},
+		{
+			name:      "elasticquota with negative max",
+			path:      ValidateElasticQuotaPath,
+			namespace: "ns2",
+			object:    makeEQ("eq2", "ns2", nil, v1.ResourceList{v1.ResourceCPU: resource.MustParse("-1")}),
+		},
+		{
+			name:      "second elasticquota in a namespace",
+			path:      ValidateElasticQuotaPath,
+			namespace: "ns1",
+			object:    makeEQ("eq2", "ns1", nil, nil),
+		},
+		{
+			name:          "elasticquota updated",
+			path:          ValidateElasticQuotaPath,
+			operation:     admissionv1.Update,
+			namespace:     "ns1",
+			object:        existingEQ,
+			expectAllowed: true,
+		},
+		{
+			name:          "pod without podgroup",
+			path:          ValidatePodPath,
+			namespace:     "ns1",
+			object:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}},
+			expectAllowed: true,
+		},
+		{
+			name:      "pod of a workload with a podgroup",
+			path:      MutatePodPath,
+			namespace: "ns1",
+			object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{"app": "job"},
+				OwnerReferences: []metav1.OwnerReference{jobOwner}}},
+			expectAllowed: true,
+			expectPatch:   `[{"op":"add","path":"/metadata/labels","value":{"app":"job","pod-group.scheduling.sigs.k8s.io":"job"}}]`,
+		},
+		{
+			name:      "pod of another workload with the name of a podgroup",
+			path:      MutatePodPath,
+			namespace: "ns1",
+			object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p",
+				OwnerReferences: []metav1.OwnerReference{otherJobOwner}}},
+			expectAllowed: true,
+		},
+		{
+			name:      "pod of a workload with its own podgroup",
+			path:      MutatePodPath,
+			namespace: "ns1",
+			object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{util.PodGroupLabel: "pg"},
+				OwnerReferences: []metav1.OwnerReference{jobOwner}}},
+			expectAllowed: true,
+		},
+		{
+			name:          "pod without owner",
+			path:          MutatePodPath,
+			namespace:     "ns1",
+			object:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}},
+			expectAllowed: true,
+		},
+		{
+			name:          "pod of an existing podgroup",
+			path:          ValidatePodPath,
+			namespace:     "ns1",
+			object:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{util.PodGroupLabel: "pg"}}},
+			expectAllowed: true,
+		},
+		{
+			name:          "pod of a podgroup that is not cached",
+			path:          ValidatePodPath,
+			namespace:     "ns1",
+			object:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{util.PodGroupLabel: "uncached"}}},
+			expectAllowed: true,
+		},
+		{
+			name:      "pod of a lightweight podgroup",
+			path:      ValidatePodPath,
+			namespace: "ns1",
+			object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{util.PodGroupLabel: "missing",
+				util.PodGroupMinAvailableLabel: "2"}}},
+			expectAllowed: true,
+		},
+		{
+			name:      "pod of a missing podgroup",
+			path:      ValidatePodPath,
+			namespace: "ns1",
+			object:    &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{util.PodGroupLabel: "missing"}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pgClient := pgfake.NewSimpleClientset(existingPG, uncachedPG, existingEQ)
+			pgInformer := schedinformer.NewSharedInformerFactory(pgClient, 0).Scheduling().V1alpha1().PodGroups()
+			pgInformer.Informer().GetStore().Add(existingPG)
+			pgInformer.Informer().GetStore().Add(jobPG)
+			pgInformer.Informer().GetStore().Add(dependentPG)
+			wh := NewWebhook(pgInformer, pgClient, util.DefaultPodGroupResolver)
+
+			response := review(t, wh, c.path, c.operation, c.namespace, c.object, c.oldObject)
+			if response.Allowed != c.expectAllowed {
+				t.Fatalf("expected allowed %v, got %v: %v", c.expectAllowed, response.Allowed, response.Result)
+			}
+			if string(response.Patch) != c.expectPatch {
+				t.Errorf("expected patch %v, got %v", c.expectPatch, string(response.Patch))
+			}
+		})
+	}
+}
+
+// review sends an AdmissionReview of the object, and of the old object if it is not nil, to the webhook,
+// and returns the response.
+func review(t *testing.T, wh *Webhook, path string, operation admissionv1.Operation, namespace string,
+	obj, oldObj runtime.Object) *admissionv1.AdmissionResponse {
+	if len(operation) == 0 {
+		operation = admissionv1.Create
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var oldRaw []byte
+	if oldObj != nil {
+		if oldRaw, err = json.Marshal(oldObj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accessor, _ := obj.(metav1.Object)
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "uid",
+			Name:      accessor.GetName(),
+			Namespace: namespace,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	wh.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %v, got %v: %v", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	result := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Response == nil || result.Response.UID != "uid" {
+		t.Fatalf("expected a response to the request, got %+v", result.Response)
+	}
+	return result.Response
+}
+
+func makeEQ(name, namespace string, min, max v1.ResourceList) *v1alpha1.ElasticQuota {
+	return &v1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v1alpha1.ElasticQuotaSpec{Min: min, Max: max},
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,6 +30,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -36,6 +39,7 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler"
 	schedapi "k8s.io/kubernetes/pkg/scheduler/apis/config"
 	fwkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	framework "k8s.io/kubernetes/pkg/scheduler/framework/v1alpha1"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 	testutils "k8s.io/kubernetes/test/integration/util"
 	imageutils "k8s.io/kubernetes/test/utils/image"
@@ -123,6 +127,11 @@ func TestCoschedulingPlugin(t *testing.T) {
 					{Name: coscheduling.Name},
 				},
 			},
+			Reserve: &schedapi.PluginSet{
+				Enabled: []schedapi.Plugin{
+					{Name: coscheduling.Name},
+				},
+			},
 			PostBind: &schedapi.PluginSet{
 				Enabled: []schedapi.Plugin{
 					{Name: coscheduling.Name},
@@ -442,6 +451,248 @@ func TestCoschedulingPlugin(t *testing.T) {
 	}
 }
 
+// failingBinder fails to bind every pod labeled with failBindLabel once, and leaves the others to the default binder.
+type failingBinder struct {
+	sync.Mutex
+	failed map[types.UID]bool
+}
+
+const failBindLabel = "fail-bind"
+
+var _ framework.BindPlugin = &failingBinder{}
+
+func (fb *failingBinder) Name() string {
+	return "FailingBinder"
+}
+
+func (fb *failingBinder) Bind(_ context.Context, _ *framework.CycleState, pod *v1.Pod, _ string) *framework.Status {
+	fb.Lock()
+	defer fb.Unlock()
+	if _, ok := pod.Labels[failBindLabel]; !ok || fb.failed[pod.UID] {
+		return framework.NewStatus(framework.Skip, "")
+	}
+	fb.failed[pod.UID] = true
+	return framework.NewStatus(framework.Error, fmt.Sprintf("failed to bind pod %v", pod.Name))
+}
+
+func TestCoschedulingBindFailurePolicy(t *testing.T) {
+	todo := context.TODO()
+	ctx, cancelFunc := context.WithCancel(todo)
+	testCtx := &testutils.TestContext{
+		Ctx:      ctx,
+		CancelFn: cancelFunc,
+		CloseFn:  func() {},
+	}
+	binder := &failingBinder{failed: map[types.UID]bool{}}
+	registry := fwkruntime.Registry{
+		coscheduling.Name: coscheduling.New,
+		binder.Name(): func(_ runtime.Object, _ framework.FrameworkHandle) (framework.Plugin, error) {
+			return binder, nil
+		},
+	}
+	t.Log("create apiserver")
+	_, config := util.StartApi(t, todo.Done())
+
+	config.ContentType = "application/json"
+
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeConfigPath := util.BuildKubeConfigFile(config)
+	if len(kubeConfigPath) == 0 {
+		t.Fatal("Build KubeConfigFile failed")
+	}
+	defer os.RemoveAll(kubeConfigPath)
+
+	t.Log("create crd")
+	if _, err := apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, makeCRD(), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := kubernetes.NewForConfigOrDie(config)
+	extClient := pgclientset.NewForConfigOrDie(config)
+
+	if err = wait.Poll(100*time.Millisecond, 3*time.Second, func() (done bool, err error) {
+		groupList, _, err := cs.ServerGroupsAndResources()
+		if err != nil {
+			return false, nil
+		}
+		for _, group := range groupList {
+			if group.Name == scheduling.GroupName {
+				return true, nil
+			}
+		}
+		t.Log("waiting for crd api ready")
+		return false, nil
+	}); err != nil {
+		t.Fatalf("Waiting for crd read time out: %v", err)
+	}
+	cfg := &scheconfig.CoschedulingArgs{
+		KubeConfigPath:           kubeConfigPath,
+		PermitWaitingTimeSeconds: 3,
+	}
+
+	coschedulingPlugins := &schedapi.PluginSet{
+		Enabled: []schedapi.Plugin{
+			{Name: coscheduling.Name},
+		},
+	}
+	profile := schedapi.KubeSchedulerProfile{
+		SchedulerName: v1.DefaultSchedulerName,
+		Plugins: &schedapi.Plugins{
+			QueueSort: &schedapi.PluginSet{
+				Enabled: []schedapi.Plugin{
+					{Name: coscheduling.Name},
+				},
+				Disabled: []schedapi.Plugin{
+					{Name: "*"},
+				},
+			},
+			PreFilter: coschedulingPlugins,
+			Permit:    coschedulingPlugins,
+			Reserve:   coschedulingPlugins,
+			PostBind:  coschedulingPlugins,
+			Bind: &schedapi.PluginSet{
+				Enabled: []schedapi.Plugin{
+					{Name: binder.Name()},
+					{Name: "DefaultBinder"},
+				},
+				Disabled: []schedapi.Plugin{
+					{Name: "*"},
+				},
+			},
+		},
+		PluginConfig: []schedapi.PluginConfig{
+			{
+				Name: coscheduling.Name,
+				Args: cfg,
+			},
+		},
+	}
+
+	ns, err := cs.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("integration-test-%v", string(uuid.NewUUID()))}}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		t.Fatalf("Failed to integration test ns: %v", err)
+	}
+
+	autoCreate := false
+	t.Logf("namespaces %+v", ns.Name)
+	_, err = cs.CoreV1().ServiceAccounts(ns.Name).Create(ctx, &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: ns.Name}, AutomountServiceAccountToken: &autoCreate}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		t.Fatalf("Failed to create ns default: %v", err)
+	}
+
+	testCtx.NS = ns
+	testCtx.ClientSet = cs
+
+	testCtx = util.InitTestSchedulerWithOptions(
+		t,
+		testCtx,
+		true,
+		scheduler.WithProfiles(profile),
+		scheduler.WithFrameworkOutOfTreeRegistry(registry),
+	)
+	t.Log("init scheduler success")
+	defer testutils.CleanupTest(t, testCtx)
+
+	// Create a Node.
+	nodeName := "fake-node"
+	node := st.MakeNode().Name("fake-node").Label("node", nodeName).Obj()
+	node.Status.Allocatable = v1.ResourceList{
+		v1.ResourcePods:   *resource.NewQuantity(32, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(300, resource.DecimalSI),
+	}
+	node.Status.Capacity = v1.ResourceList{
+		v1.ResourcePods:   *resource.NewQuantity(32, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(300, resource.DecimalSI),
+	}
+	node, err = cs.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create Node %q: %v", nodeName, err)
+	}
+	pause := imageutils.GetPauseImageName()
+	withPolicy := func(pg *v1alpha1.PodGroup, policy v1alpha1.BindFailurePolicy) *v1alpha1.PodGroup {
+		pg.Spec.BindFailurePolicy = policy
+		return pg
+	}
+	for _, tt := range []struct {
+		name            string
+		pods            []*v1.Pod
+		podGroups       []*v1alpha1.PodGroup
+		expectedPods    []string
+		expectedEvicted []string
+	}{
+		{
+			name: "member failed to be bound is re-queued and scheduled",
+			pods: []*v1.Pod{
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t1-p1").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg1").ZeroTerminationGracePeriod().Obj(), pause),
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t1-p2").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg1").ZeroTerminationGracePeriod().Obj(), pause),
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t1-p3").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg1").Label(failBindLabel, "").ZeroTerminationGracePeriod().Obj(), pause),
+			},
+			podGroups: []*v1alpha1.PodGroup{
+				withPolicy(util.MakePG("pg1", ns.Name, 3, nil, nil), v1alpha1.BindFailureRequeue),
+			},
+			expectedPods: []string{"t1-p1", "t1-p2", "t1-p3"},
+		},
+		{
+			name: "members bound are evicted when a member failed to be bound",
+			pods: []*v1.Pod{
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t2-p1").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg2").ZeroTerminationGracePeriod().Obj(), pause),
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t2-p2").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg2").ZeroTerminationGracePeriod().Obj(), pause),
+				WithContainer(st.MakePod().Namespace(ns.Name).Name("t2-p3").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).Priority(
+					midPriority).Label(coschedulingutil.PodGroupLabel, "pg2").Label(failBindLabel, "").ZeroTerminationGracePeriod().Obj(), pause),
+			},
+			podGroups: []*v1alpha1.PodGroup{
+				withPolicy(util.MakePG("pg2", ns.Name, 3, nil, nil), v1alpha1.BindFailureRollback),
+			},
+			expectedEvicted: []string{"t2-p1", "t2-p2"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Logf("Start-coscheduling-test %v", tt.name)
+			defer cleanupPodGroups(ctx, extClient, tt.podGroups)
+			// create pod group
+			if err := createPodGroups(ctx, extClient, tt.podGroups); err != nil {
+				t.Fatal(err)
+			}
+			defer testutils.CleanupPods(cs, t, tt.pods)
+			for i := range tt.pods {
+				klog.Infof("Creating pod %v", tt.pods[i].Name)
+				_, err := cs.CoreV1().Pods(tt.pods[i].Namespace).Create(testCtx.Ctx, tt.pods[i], metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("Failed to create Pod %q: %v", tt.pods[i].Name, err)
+				}
+			}
+			err = wait.Poll(1*time.Second, 120*time.Second, func() (bool, error) {
+				for _, v := range tt.expectedPods {
+					if !podScheduled(cs, ns.Name, v) {
+						return false, nil
+					}
+				}
+				for _, v := range tt.expectedEvicted {
+					if !podNotExist(cs, ns.Name, v) {
+						return false, nil
+					}
+				}
+				return true, nil
+			})
+			if err != nil {
+				t.Fatalf("%v Waiting expectedPods error: %v", tt.name, err.Error())
+			}
+			t.Logf("case %v finished", tt.name)
+		})
+	}
+}
+
 func makeCRD() *apiextensionsv1.CustomResourceDefinition {
 	var min = 1.0
 	return &apiextensionsv1.CustomResourceDefinition{
@@ -454,6 +705,7 @@ func makeCRD() *apiextensionsv1.CustomResourceDefinition {
 		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
 			Group: scheduling.GroupName,
 			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1alpha1", Served: true, Storage: true,
+				Subresources: &apiextensionsv1.CustomResourceSubresources{Status: &apiextensionsv1.CustomResourceSubresourceStatus{}},
 				Schema: &apiextensionsv1.CustomResourceValidation{
 					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
 						Type: "object",
@@ -465,6 +717,9 @@ func makeCRD() *apiextensionsv1.CustomResourceDefinition {
 										Type:    "integer",
 										Minimum: &min,
 									},
+									"bindFailurePolicy": {
+										Type: "string",
+									},
 									"minResources": {
 										Type: "object",
 										AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
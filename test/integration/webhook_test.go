@@ -0,0 +1,306 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/cert"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling"
+	"sigs.k8s.io/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
+	schedinformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+	pgutil "sigs.k8s.io/scheduler-plugins/pkg/util"
+	"sigs.k8s.io/scheduler-plugins/pkg/webhook"
+	"sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+const webhookTestNamespace = "webhook"
+
+func TestWebhook(t *testing.T) {
+	todo := context.TODO()
+	ctx, cancelFunc := context.WithCancel(todo)
+	defer cancelFunc()
+
+	t.Log("create apiserver")
+	_, config := util.StartApi(t, todo.Done())
+	config.ContentType = "application/json"
+
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fields of the spec of PodGroups are kept, so that all of them are validated.
+	pgCRD := makeCRD()
+	preserveUnknownFields := true
+	specSchema := pgCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	specSchema.XPreserveUnknownFields = &preserveUnknownFields
+	pgCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"] = specSchema
+
+	t.Log("create crd")
+	for _, crd := range []*apiextensionsv1.CustomResourceDefinition{pgCRD, makeElasticQuotaCRD()} {
+		if _, err := apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, crd, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cs := kubernetes.NewForConfigOrDie(config)
+	extClient := versioned.NewForConfigOrDie(config)
+
+	if err = wait.Poll(100*time.Millisecond, 3*time.Second, func() (done bool, err error) {
+		groupList, _, err := cs.ServerGroupsAndResources()
+		if err != nil {
+			return false, nil
+		}
+		for _, group := range groupList {
+			if group.Name == scheduling.GroupName {
+				return true, nil
+			}
+		}
+		t.Log("waiting for crd api ready")
+		return false, nil
+	}); err != nil {
+		t.Fatalf("Waiting for crd read time out: %v", err)
+	}
+
+	_, err = cs.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: webhookTestNamespace, Labels: map[string]string{"webhook": "enabled"}}}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		t.Fatalf("Failed to integration test ns: %v", err)
+	}
+	autoCreate := false
+	_, err = cs.CoreV1().ServiceAccounts(webhookTestNamespace).Create(ctx, &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: webhookTestNamespace}, AutomountServiceAccountToken: &autoCreate}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		t.Fatalf("Failed to create ns default: %v", err)
+	}
+
+	t.Log("start webhook")
+	addr, caBundle := startWebhook(t, ctx, extClient)
+	if err := createWebhookConfigurations(ctx, cs, addr, caBundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// The apiserver calls the webhooks once it has observed their configurations.
+	negative := resource.MustParse("-1")
+	if err = wait.Poll(100*time.Millisecond, 10*time.Second, func() (done bool, err error) {
+		eq := &v1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: webhookTestNamespace},
+			Spec:       v1alpha1.ElasticQuotaSpec{Max: v1.ResourceList{v1.ResourceCPU: negative}},
+		}
+		_, err = extClient.SchedulingV1alpha1().ElasticQuotas(webhookTestNamespace).Create(ctx, eq, metav1.CreateOptions{})
+		if err == nil {
+			extClient.SchedulingV1alpha1().ElasticQuotas(webhookTestNamespace).Delete(ctx, eq.Name, metav1.DeleteOptions{})
+			t.Log("waiting for webhooks ready")
+			return false, nil
+		}
+		return errors.IsInvalid(err), nil
+	}); err != nil {
+		t.Fatalf("Waiting for webhooks time out: %v", err)
+	}
+
+	t.Run("podgroup defaulted", func(t *testing.T) {
+		pg := &v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: webhookTestNamespace},
+			Spec:       v1alpha1.PodGroupSpec{DependsOn: []v1alpha1.PodGroupDependency{{Name: "pg0"}}},
+		}
+		pg, err := extClient.SchedulingV1alpha1().PodGroups(webhookTestNamespace).Create(ctx, pg, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pg.Spec.MinMember != 1 || pg.Spec.DependsOn[0].Phase != v1alpha1.PodGroupRunning {
+			t.Errorf("expected the defaults of the PodGroup to be set, got %+v", pg.Spec)
+		}
+	})
+
+	t.Run("invalid podgroup", func(t *testing.T) {
+		maxMember := int32(1)
+		pg := util.MakePG("pg2", webhookTestNamespace, 2, nil, nil)
+		pg.Spec.MaxMember = &maxMember
+		_, err := extClient.SchedulingV1alpha1().PodGroups(webhookTestNamespace).Create(ctx, pg, metav1.CreateOptions{})
+		if !errors.IsInvalid(err) {
+			t.Errorf("expected the PodGroup to be rejected as invalid, got %v", err)
+		}
+	})
+
+	t.Run("elasticquota defaulted and validated", func(t *testing.T) {
+		eq := &v1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "eq1", Namespace: webhookTestNamespace},
+			Spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+				Max: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+		}
+		eq, err := extClient.SchedulingV1alpha1().ElasticQuotas(webhookTestNamespace).Create(ctx, eq, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cpu, memory := eq.Spec.Max[v1.ResourceCPU], eq.Spec.Min[v1.ResourceMemory]
+		if cpu.Cmp(resource.MustParse("2")) != 0 || !memory.IsZero() {
+			t.Errorf("expected the defaults of the ElasticQuota to be set, got %+v", eq.Spec)
+		}
+
+		eq.Spec.Min[v1.ResourceCPU] = resource.MustParse("3")
+		if _, err := extClient.SchedulingV1alpha1().ElasticQuotas(webhookTestNamespace).Update(ctx, eq, metav1.UpdateOptions{}); !errors.IsInvalid(err) {
+			t.Errorf("expected an ElasticQuota with min greater than max to be rejected as invalid, got %v", err)
+		}
+
+		another := &v1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Name: "eq2", Namespace: webhookTestNamespace}}
+		if _, err := extClient.SchedulingV1alpha1().ElasticQuotas(webhookTestNamespace).Create(ctx, another, metav1.CreateOptions{}); !errors.IsInvalid(err) {
+			t.Errorf("expected a second ElasticQuota in the namespace to be rejected as invalid, got %v", err)
+		}
+	})
+
+	t.Run("pods of podgroups", func(t *testing.T) {
+		pause := imageutils.GetPauseImageName()
+		pod := WithContainer(st.MakePod().Namespace(webhookTestNamespace).Name("p1").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).
+			Label(pgutil.PodGroupLabel, "pg1").ZeroTerminationGracePeriod().Obj(), pause)
+		if _, err := cs.CoreV1().Pods(webhookTestNamespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Errorf("expected a pod of an existing PodGroup to be created, got %v", err)
+		}
+
+		pod = WithContainer(st.MakePod().Namespace(webhookTestNamespace).Name("p2").Req(map[v1.ResourceName]string{v1.ResourceMemory: "50"}).
+			Label(pgutil.PodGroupLabel, "missing").ZeroTerminationGracePeriod().Obj(), pause)
+		if _, err := cs.CoreV1().Pods(webhookTestNamespace).Create(ctx, pod, metav1.CreateOptions{}); !errors.IsForbidden(err) {
+			t.Errorf("expected a pod of a missing PodGroup to be forbidden, got %v", err)
+		}
+	})
+}
+
+// startWebhook serves the webhooks on a local port with a self-signed certificate, and returns the address
+// of the webhooks and the CA bundle of the certificate.
+func startWebhook(t *testing.T, ctx context.Context, extClient versioned.Interface) (string, []byte) {
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey("127.0.0.1", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certDir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-ctx.Done()
+		os.RemoveAll(certDir)
+	}()
+	certFile, keyFile := path.Join(certDir, "tls.crt"), path.Join(certDir, "tls.key")
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	pgInformerFactory := schedinformer.NewSharedInformerFactory(extClient, 0)
+	wh := webhook.NewWebhook(pgInformerFactory.Scheduling().V1alpha1().PodGroups(), extClient, pgutil.DefaultPodGroupResolver)
+	pgInformerFactory.Start(ctx.Done())
+	go func() {
+		if err := wh.Run(addr, certFile, keyFile, ctx.Done()); err != nil {
+			t.Errorf("Error serving webhooks: %v", err)
+		}
+	}()
+	return addr, certPEM
+}
+
+// createWebhookConfigurations registers the webhooks served at addr with the apiserver. Pods are only
+// validated in the test namespace.
+func createWebhookConfigurations(ctx context.Context, cs kubernetes.Interface, addr string, caBundle []byte) error {
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	makeWebhook := func(name, path string, rule admissionregistrationv1.RuleWithOperations) admissionregistrationv1.ValidatingWebhook {
+		url := fmt.Sprintf("https://%v%v", addr, path)
+		return admissionregistrationv1.ValidatingWebhook{
+			Name:                    name,
+			ClientConfig:            admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: caBundle},
+			Rules:                   []admissionregistrationv1.RuleWithOperations{rule},
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+		}
+	}
+	makeRule := func(group, version, resource string, operations ...admissionregistrationv1.OperationType) admissionregistrationv1.RuleWithOperations {
+		return admissionregistrationv1.RuleWithOperations{
+			Operations: operations,
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{group},
+				APIVersions: []string{version},
+				Resources:   []string{resource},
+			},
+		}
+	}
+	pgRule := makeRule(scheduling.GroupName, "v1alpha1", "podgroups", admissionregistrationv1.Create, admissionregistrationv1.Update)
+	eqRule := makeRule(scheduling.GroupName, "v1alpha1", "elasticquotas", admissionregistrationv1.Create, admissionregistrationv1.Update)
+
+	var mutatingWebhooks []admissionregistrationv1.MutatingWebhook
+	for _, validating := range []admissionregistrationv1.ValidatingWebhook{
+		makeWebhook("podgroups.scheduling.sigs.k8s.io", webhook.MutatePodGroupPath, pgRule),
+		makeWebhook("elasticquotas.scheduling.sigs.k8s.io", webhook.MutateElasticQuotaPath, eqRule),
+	} {
+		mutatingWebhooks = append(mutatingWebhooks, admissionregistrationv1.MutatingWebhook{
+			Name:                    validating.Name,
+			ClientConfig:            validating.ClientConfig,
+			Rules:                   validating.Rules,
+			FailurePolicy:           validating.FailurePolicy,
+			SideEffects:             validating.SideEffects,
+			AdmissionReviewVersions: validating.AdmissionReviewVersions,
+		})
+	}
+	if _, err := cs.AdmissionregistrationV1().MutatingWebhookConfigurations().Create(ctx, &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scheduler-plugins"},
+		Webhooks:   mutatingWebhooks,
+	}, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+
+	podWebhook := makeWebhook("pods.scheduling.sigs.k8s.io", webhook.ValidatePodPath, makeRule("", "v1", "pods", admissionregistrationv1.Create))
+	podWebhook.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"webhook": "enabled"}}
+	_, err := cs.AdmissionregistrationV1().ValidatingWebhookConfigurations().Create(ctx, &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "scheduler-plugins"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			makeWebhook("podgroups.scheduling.sigs.k8s.io", webhook.ValidatePodGroupPath, pgRule),
+			makeWebhook("elasticquotas.scheduling.sigs.k8s.io", webhook.ValidateElasticQuotaPath, eqRule),
+			podWebhook,
+		},
+	}, metav1.CreateOptions{})
+	return err
+}